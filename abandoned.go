@@ -0,0 +1,58 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// AbandonedPolicy is what happens to an object borrowed for longer than the borrow timeout
+type AbandonedPolicy int
+
+const (
+	// AbandonedReclaim expires the object
+	AbandonedReclaim AbandonedPolicy = iota
+	// AbandonedLogOnly leaves the object alone, only warning about it once
+	AbandonedLogOnly
+	// AbandonedReclaimAndReplace expires the object and creates an idle one in its place
+	AbandonedReclaimAndReplace
+)
+
+// OnAbandoned sets the policy for objects borrowed for longer than the borrow timeout. Defaults to AbandonedReclaim.
+func OnAbandoned[T any](policy AbandonedPolicy) Option[T] {
+	return func(p *Pool[T]) {
+		p.abandonedPolicy = policy
+	}
+}
+
+// abandoned handles an object borrowed for longer than the borrow timeout, returning true if it was reclaimed
+func (p *Pool[T]) abandoned(ctx context.Context, o *T, e *entry, now time.Time) (bool, error) {
+	if p.abandonedPolicy == AbandonedLogOnly {
+		if !e.reported {
+			e.reported = true
+			p.warnLogger(p.withInfo(ctx), "object borrowed for longer than the borrow timeout",
+				slog.String("object", p.identify(o)), slog.String("label", e.label), slog.Duration("held", now.Sub(e.lastBorrowed)))
+		}
+		return false, nil
+	}
+
+	p.held(e, now)
+	delete(p.locked, o)
+	p.destroy(ctx, o)
+	p.emit(EventReclaimed, o, nil)
+
+	if p.abandonedPolicy == AbandonedReclaimAndReplace && p.objectCount() < p.size && p.allowCreate() {
+		n, err := p.newObject(ctx)
+		if errors.Is(err, errNoBudget) {
+			return true, nil
+		}
+		if err != nil {
+			return true, fmt.Errorf("on replacing an abandoned object: %w", err)
+		}
+		p.unlocked[n] = p.newEntry()
+	}
+
+	return true, nil
+}
@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// BorrowFor borrows the object previously borrowed for the key, if it is idle,
+// falling back to any idle object or a new one. The borrowed object becomes bound to the key.
+// The key must be comparable, like a map key. Only the last keys of each object are kept, see MaxAffinityKeys.
+func (p *Pool[T]) BorrowFor(ctx context.Context, key any, options ...BorrowOption) (*T, error) {
+	if key != nil && !reflect.TypeOf(key).Comparable() {
+		return nil, fmt.Errorf("on borrow for: key of type %T is not comparable", key)
+	}
+	o, _, err := p.borrow(ctx, borrowRequest[T]{key: key}, newBorrowConfig(options))
+	return o, err
+}
+
+// MaxAffinityKeys sets how many keys can be bound to an object, forgetting the oldest key beyond it,
+// so that the bindings do not grow without bound with per request or per user keys. Defaults to 16.
+func MaxAffinityKeys[T any](n int) Option[T] {
+	return func(p *Pool[T]) {
+		p.maxAffinityKeys = max(n, 1)
+	}
+}
+
+// defaultAffinityKeys is how many keys can be bound to an object, unless set by MaxAffinityKeys
+const defaultAffinityKeys = 16
+
+// bind binds the key to the object, replacing a previous binding of the key
+func (p *Pool[T]) bind(key any, o *T) {
+	if key == nil {
+		return
+	}
+
+	if prev, ok := p.affinity[key]; ok {
+		if prev == o {
+			return
+		}
+		p.unbindKey(prev, key)
+	}
+
+	keys := p.affinityKeys[o]
+	limit := p.maxAffinityKeys
+	if limit == 0 {
+		limit = defaultAffinityKeys
+	}
+	if len(keys) >= limit {
+		delete(p.affinity, keys[0])
+		keys = append(keys[:0], keys[1:]...)
+	}
+	p.affinity[key] = o
+	p.affinityKeys[o] = append(keys, key)
+}
+
+// unbindKey removes the key from the keys bound to the object
+func (p *Pool[T]) unbindKey(o *T, key any) {
+	keys := p.affinityKeys[o]
+	for i, k := range keys {
+		if k == key {
+			keys = append(keys[:i], keys[i+1:]...)
+			break
+		}
+	}
+	if len(keys) == 0 {
+		delete(p.affinityKeys, o)
+		return
+	}
+	p.affinityKeys[o] = keys
+}
+
+// unbind removes all the keys bound to the object
+func (p *Pool[T]) unbind(o *T) {
+	for _, key := range p.affinityKeys[o] {
+		delete(p.affinity, key)
+	}
+	delete(p.affinityKeys, o)
+}
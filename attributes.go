@@ -0,0 +1,46 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+)
+
+type infoKey struct{}
+
+// Info identifies the pool calling a callback
+type Info struct {
+	Name       string
+	Attributes []slog.Attr
+}
+
+// Attributes sets custom attributes identifying the pool, besides its name.
+// They are added to the default error logger and to the context passed to the callbacks.
+func Attributes[T any](attrs ...slog.Attr) Option[T] {
+	return func(p *Pool[T]) {
+		p.attributes = append(p.attributes, attrs...)
+	}
+}
+
+// InfoFromContext returns the identification of the pool that called a callback, like create, validate, expire or the error logger.
+func InfoFromContext(ctx context.Context) (Info, bool) {
+	info, ok := ctx.Value(infoKey{}).(Info)
+	return info, ok
+}
+
+// Attributes returns the pool name, as "pool", followed by the custom attributes.
+func (p *Pool[T]) Attributes() []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(p.attributes)+1)
+	attrs = append(attrs, slog.String("pool", p.name))
+	return append(attrs, p.attributes...)
+}
+
+// withInfo adds the pool identification to the context, if it has any
+func (p *Pool[T]) withInfo(ctx context.Context) context.Context {
+	if p.name == "" && len(p.attributes) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, infoKey{}, Info{
+		Name:       p.name,
+		Attributes: p.attributes,
+	})
+}
@@ -0,0 +1,71 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// AutoScaling configures the automatic adjustment of the pool size, evaluated on each janitor run.
+type AutoScaling struct {
+	// Min and Max bound the pool size
+	Min, Max int
+	// Step is how much the size changes on each adjustment. Defaults to 1.
+	Step int
+	// GrowAfter is how long borrowers must be waiting for the size to grow
+	GrowAfter time.Duration
+	// ShrinkBelow is the utilization, borrowed objects over the size, below which the size shrinks
+	ShrinkBelow float64
+	// ShrinkAfter is how long the utilization must stay below ShrinkBelow for the size to shrink
+	ShrinkAfter time.Duration
+}
+
+// AutoScale enables the automatic adjustment of the pool size.
+// The initial size is the one set by Size, bounded by the auto scaling limits.
+func AutoScale[T any](cfg AutoScaling) Option[T] {
+	return func(p *Pool[T]) {
+		if cfg.Min < 1 {
+			cfg.Min = 1
+		}
+		if cfg.Max < cfg.Min {
+			cfg.Max = cfg.Min
+		}
+		if cfg.Step < 1 {
+			cfg.Step = 1
+		}
+		p.autoScaling = &cfg
+	}
+}
+
+// autoScale grows or shrinks the pool size according to the pressure of the waiters and the utilization
+func (p *Pool[T]) autoScale(ctx context.Context, now time.Time) {
+	cfg := p.autoScaling
+	if cfg == nil {
+		return
+	}
+
+	p.size = min(max(p.size, cfg.Min), cfg.Max)
+
+	if p.waiters > 0 && now.Sub(p.waitersSince) >= cfg.GrowAfter {
+		p.size = min(p.size+cfg.Step, cfg.Max)
+		p.lowUsageSince = time.Time{}
+		p.signal()
+		return
+	}
+
+	if float64(len(p.locked))/float64(p.size) >= cfg.ShrinkBelow {
+		p.lowUsageSince = time.Time{}
+		return
+	}
+	if p.lowUsageSince.IsZero() {
+		p.lowUsageSince = now
+	}
+	if now.Sub(p.lowUsageSince) < cfg.ShrinkAfter {
+		return
+	}
+
+	p.size = max(p.size-cfg.Step, cfg.Min)
+	p.lowUsageSince = now
+	// drop the idle objects beyond the new size
+	for p.objectCount() > p.size && p.evictIdle(ctx) {
+	}
+}
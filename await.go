@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// waitState is the state of a borrow waiting for objects, shared by Borrow and BorrowN
+type waitState struct {
+	// ctx is the context of the borrower and waitCtx bounds its wait, see WaitTimeout and WaitShare
+	ctx     context.Context
+	waitCtx context.Context
+	cancel  context.CancelFunc
+	waiter  waiter
+	// start is when the borrower started waiting
+	start time.Time
+	// ticket is the place of the borrower in the wait queue
+	ticket uint64
+	// abandoned reports a borrow given up while waiting, see OnBorrowAbandoned
+	abandoned func()
+}
+
+// startWait prepares the wait of a borrow of n objects
+func (p *Pool[T]) startWait(ctx context.Context, cfg borrowConfig, n int) waitState {
+	waitCtx, cancel := p.waitContext(ctx)
+	return waitState{
+		ctx:     ctx,
+		waitCtx: waitCtx,
+		cancel:  cancel,
+		waiter:  waiter{cfg.priority, cfg.class, n},
+	}
+}
+
+// endWait ends the wait of a borrow. It must be called while holding the lock.
+func (p *Pool[T]) endWait(ws *waitState) {
+	ws.cancel()
+	p.warnSlow(ws.ctx, "slow borrow wait", ws.start, p.slowBorrowWait)
+	p.dequeue(ws.ticket)
+}
+
+// reportAbandoned reports a borrow given up while waiting. It must be called without holding the lock.
+func (ws *waitState) reportAbandoned() {
+	if ws.abandoned != nil {
+		ws.abandoned()
+	}
+}
+
+// await waits for objects to be released, describing a failure as the given operation.
+// It must be called while holding the lock.
+func (p *Pool[T]) await(ws *waitState, op string) error {
+	// The sequence is taken while holding the pool lock, so that a release
+	// happening before we start waiting is not missed.
+	seq := p.cond.Sequence()
+	if ws.start.IsZero() {
+		ws.start = time.Now()
+		ws.ticket = p.enqueue()
+		p.checkReentrancy(ws.ctx)
+	}
+	p.waiting(ws.waiter, 1)
+	p.mutex.Unlock()
+	err := p.cond.WaitSeq(ws.waitCtx, seq)
+	p.mutex.Lock()
+	p.waiting(ws.waiter, -1)
+	if err == nil {
+		return nil
+	}
+
+	// lower priority borrowers may have been waiting for us to give up
+	p.signal()
+	ws.abandoned = p.gaveUp(ws.ctx, ws.start, ws.ticket)
+	if errors.Is(context.Cause(ws.waitCtx), errWaitTimeout) {
+		return fmt.Errorf("on %s: %w: %w", op, errWaitTimeout, ErrPoolExhausted)
+	}
+	if ws.ctx.Err() == nil {
+		return fmt.Errorf("on %s: wait budget exhausted: %w", op, ErrPoolExhausted)
+	}
+	return fmt.Errorf("on %s while waiting: %w", op, err)
+}
@@ -0,0 +1,31 @@
+package pool
+
+import "context"
+
+// BaseContext sets the context whose values, like credentials, tenant or logger, are seen by create, validate and expire,
+// instead of the values of the context of the caller, like a borrower, so that the objects do not depend on request scoped values.
+// The cancellation still comes from the caller, except for creations for a borrower,
+// which go on for the create grace after the borrower gives up, see CreateGrace.
+func BaseContext[T any](base context.Context) Option[T] {
+	return func(p *Pool[T]) {
+		p.baseContext = base
+	}
+}
+
+// baseContext has the values of the base context and the cancellation of the call context
+type baseContext struct {
+	context.Context
+	base context.Context
+}
+
+func (c baseContext) Value(key any) any {
+	return c.base.Value(key)
+}
+
+// callbackContext is the context passed to create, validate and expire
+func (p *Pool[T]) callbackContext(ctx context.Context) context.Context {
+	if p.baseContext != nil {
+		ctx = baseContext{Context: ctx, base: p.baseContext}
+	}
+	return p.withInfo(ctx)
+}
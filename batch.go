@@ -0,0 +1,199 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BorrowN borrows n objects as a unit: either all of them are borrowed or none is.
+// While waiting, no object is held, so concurrent batch borrowers cannot deadlock each other.
+// It waits and creates objects like Borrow, and each object goes through the borrow middlewares once the batch is borrowed.
+func (p *Pool[T]) BorrowN(ctx context.Context, n int, options ...BorrowOption) ([]*T, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("on borrow n: invalid number of objects %d", n)
+	}
+	cfg := newBorrowConfig(options)
+
+	objs, err := p.borrowObjects(ctx, n, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.intercept(ctx, objs)
+}
+
+func (p *Pool[T]) borrowObjects(ctx context.Context, n int, cfg borrowConfig) ([]*T, error) {
+	if err := p.admission.admit(ctx, n, cfg); err != nil {
+		return nil, fmt.Errorf("on borrow n admission: %w", err)
+	}
+
+	ws := p.startWait(ctx, cfg, n)
+	// a borrow given up while waiting is reported after releasing the lock
+	defer ws.reportAbandoned()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	defer p.endWait(&ws)
+
+	if n > p.size {
+		return nil, fmt.Errorf("on borrow n: %d objects exceed the pool size %d: %w", n, p.size, ErrPoolExhausted)
+	}
+
+	if p.closed {
+		p.violation("borrow on a closed pool")
+	}
+
+	// if the creation of the missing objects was already allowed by the rate limit
+	permitted := false
+	for {
+		if p.closed {
+			return nil, fmt.Errorf("on borrow n: %w", ErrPoolClosed)
+		}
+		if err := p.maintenance(); err != nil {
+			return nil, fmt.Errorf("on borrow n: %w", err)
+		}
+
+		if !p.outranked(cfg.priority) && p.admissible(cfg.class, n) && len(p.unlocked)+p.size-p.objectCount() >= n {
+			missing := n - len(p.unlocked)
+			if missing > 0 && !p.healthy(time.Now()) {
+				return nil, fmt.Errorf("on borrow n: %w", ErrUnhealthy)
+			}
+			if missing > 0 && !permitted {
+				waited, err := p.throttle(ctx, missing)
+				if err != nil {
+					return nil, fmt.Errorf("on borrow n while throttled: %w", err)
+				}
+				permitted = true
+				if waited {
+					continue
+				}
+			}
+
+			objs, err := p.borrowN(ctx, n, cfg)
+			if err == nil {
+				return objs, nil
+			}
+			if !errors.Is(err, errNoBudget) {
+				return nil, fmt.Errorf("on borrow n: %w", err)
+			}
+			// the shared budget is exhausted, so we wait for it to be released
+		}
+
+		if cfg.noWait {
+			return nil, fmt.Errorf("on borrow n: %w", ErrPoolExhausted)
+		}
+
+		if err := p.await(&ws, "borrow n"); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// borrowN borrows n objects, knowing that there is room for them, or none on failure.
+// Missing objects are created like for Borrow, without holding the lock.
+func (p *Pool[T]) borrowN(ctx context.Context, n int, cfg borrowConfig) ([]*T, error) {
+	objs := make([]*T, 0, n)
+	// every invalid idle object that is expired frees room for a new one
+	for o, e := range p.unlocked {
+		if len(objs) == n {
+			break
+		}
+		ok, _, err := p.takeIdle(ctx, o, e, cfg)
+		if err != nil {
+			p.releaseAll(ctx, objs)
+			return nil, err
+		}
+		if ok {
+			objs = append(objs, o)
+		}
+	}
+
+	// the room for the missing objects is reserved up front,
+	// so that concurrent borrows do not take it while the lock is released to create them
+	reserved := n - len(objs)
+	p.creatingFor(cfg.class, reserved)
+	defer func() {
+		if reserved > 0 {
+			p.creatingFor(cfg.class, -reserved)
+			p.signal()
+		}
+	}()
+
+	for len(objs) < n {
+		p.missed(time.Now())
+		// handed over to the creation
+		p.creatingFor(cfg.class, -1)
+		reserved--
+		o, err := p.createForBorrow(ctx, cfg.class)
+		if err != nil {
+			p.releaseAll(ctx, objs)
+			return nil, err
+		}
+		e := p.newEntry()
+		e.borrow(e.createdAt, cfg)
+		p.locked[o] = e
+		p.emit(EventBorrowed, o, nil)
+		p.borrowed(e)
+		p.trackPeaks()
+		objs = append(objs, o)
+	}
+
+	return objs, nil
+}
+
+// intercept passes each object of a batch through the borrow middlewares, as if it was borrowed alone.
+// If a middleware fails, the whole batch is returned.
+func (p *Pool[T]) intercept(ctx context.Context, objs []*T) ([]*T, error) {
+	if len(p.middlewares) == 0 {
+		return objs, nil
+	}
+
+	ctx = p.withInfo(ctx)
+	out := make([]*T, 0, len(objs))
+	for i, o := range objs {
+		next := func(context.Context) (*T, error) {
+			return o, nil
+		}
+		for j := len(p.middlewares) - 1; j >= 0; j-- {
+			mw, inner := p.middlewares[j], next
+			next = func(ctx context.Context) (*T, error) {
+				return mw.Borrow(ctx, inner)
+			}
+		}
+		got, err := next(ctx)
+		if err != nil {
+			// the objects handed out by the middlewares are given back through them
+			for _, o := range out {
+				p.giveBack(ctx, o)
+			}
+			for _, o := range objs[i:] {
+				p.releaseLocked(ctx, o)
+			}
+			return nil, fmt.Errorf("on borrow n: %w", err)
+		}
+		out = append(out, got)
+	}
+	return out, nil
+}
+
+// ReturnN returns all the objects to the pool.
+func (p *Pool[T]) ReturnN(ctx context.Context, objs []*T) {
+	if len(p.middlewares) > 0 {
+		for _, o := range objs {
+			p.giveBack(ctx, o)
+		}
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.releaseAll(ctx, objs)
+}
+
+func (p *Pool[T]) releaseAll(ctx context.Context, objs []*T) {
+	for _, o := range objs {
+		p.release(ctx, o)
+	}
+}
@@ -0,0 +1,59 @@
+package pool_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quintans/pool"
+)
+
+func BenchmarkBorrowReturn(b *testing.B) {
+	ctx := context.Background()
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](1),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer p.Close(ctx)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		o, err := p.Borrow(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		p.Return(ctx, o)
+	}
+}
+
+func BenchmarkBorrowReturnParallel(b *testing.B) {
+	ctx := context.Background()
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](64),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer p.Close(ctx)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			o, err := p.Borrow(ctx)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			p.Return(ctx, o)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package pool
+
+type borrowConfig struct {
+	noWait      bool
+	preferFresh bool
+	validate    bool
+	label       string
+	class       string
+	priority    int
+}
+
+func newBorrowConfig(options []BorrowOption) borrowConfig {
+	if len(options) == 0 {
+		// the options would make the config escape to the heap
+		return borrowConfig{validate: true}
+	}
+	return applyBorrowOptions(options)
+}
+
+func applyBorrowOptions(options []BorrowOption) borrowConfig {
+	cfg := borrowConfig{
+		validate: true,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// BorrowOption changes how a single Borrow acquires an object
+type BorrowOption func(*borrowConfig)
+
+// WithNoWait fails with ErrPoolExhausted, instead of waiting, when no object is available.
+func WithNoWait() BorrowOption {
+	return func(c *borrowConfig) {
+		c.noWait = true
+	}
+}
+
+// IsNoWait reports if the options include WithNoWait, for other implementations of Borrower.
+func IsNoWait(options ...BorrowOption) bool {
+	return applyBorrowOptions(options).noWait
+}
+
+// WithPreferFresh creates a new object instead of reusing an idle one, if the pool size allows it.
+func WithPreferFresh() BorrowOption {
+	return func(c *borrowConfig) {
+		c.preferFresh = true
+	}
+}
+
+// WithLabel identifies the borrower, as seen in Inspect, and aggregates its borrows in Stats.
+func WithLabel(label string) BorrowOption {
+	return func(c *borrowConfig) {
+		c.label = label
+	}
+}
+
+// WithPriority sets the priority of the borrower while waiting for an object.
+// Waiting borrowers with a higher priority are served first. The default is 0.
+func WithPriority(priority int) BorrowOption {
+	return func(c *borrowConfig) {
+		c.priority = priority
+	}
+}
+
+// WithValidate sets if idle objects are validated before being handed out. The default is true.
+func WithValidate(validate bool) BorrowOption {
+	return func(c *borrowConfig) {
+		c.validate = validate
+	}
+}
+
+// WithClass sets the class of the borrower, allowing it to use the capacity reserved for that class.
+func WithClass(class string) BorrowOption {
+	return func(c *borrowConfig) {
+		c.class = class
+	}
+}
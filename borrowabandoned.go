@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// waitQueue keeps the borrowers waiting for an object in arrival order
+type waitQueue struct {
+	hook func(ctx context.Context, waited time.Duration, position int)
+	// last ticket handed out
+	last    uint64
+	tickets map[uint64]struct{}
+}
+
+// OnBorrowAbandoned calls hook when a borrower gives up waiting for an object,
+// because its context is done or the wait timeout expired, with how long it waited and its position in the wait queue, starting at 1.
+// It measures the demand shed due to exhaustion. It is called without holding the pool lock.
+func OnBorrowAbandoned[T any](hook func(ctx context.Context, waited time.Duration, position int)) Option[T] {
+	return func(p *Pool[T]) {
+		p.queue = &waitQueue{
+			hook:    hook,
+			tickets: map[uint64]struct{}{},
+		}
+	}
+}
+
+// enqueue hands out a ticket to a new waiter, if abandoned borrows are reported
+func (p *Pool[T]) enqueue() uint64 {
+	if p.queue == nil {
+		return 0
+	}
+	p.queue.last++
+	p.queue.tickets[p.queue.last] = struct{}{}
+	return p.queue.last
+}
+
+// dequeue removes the waiter holding the ticket, if any
+func (p *Pool[T]) dequeue(ticket uint64) {
+	if p.queue == nil || ticket == 0 {
+		return
+	}
+	delete(p.queue.tickets, ticket)
+}
+
+// gaveUp returns the report of a borrow given up while waiting, to be called without holding the lock
+func (p *Pool[T]) gaveUp(ctx context.Context, waitStart time.Time, ticket uint64) func() {
+	if p.queue == nil {
+		return nil
+	}
+	waited := time.Since(waitStart)
+	// waiters that arrived earlier are ahead in the queue
+	position := 1
+	for t := range p.queue.tickets {
+		if t < ticket {
+			position++
+		}
+	}
+	hook := p.queue.hook
+	ctx = p.withInfo(ctx)
+	return func() {
+		hook(ctx, waited, position)
+	}
+}
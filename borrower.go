@@ -0,0 +1,13 @@
+package pool
+
+import "context"
+
+// Borrower is the behaviour of a pool consumed by clients, allowing it to be replaced by a test double.
+type Borrower[T any] interface {
+	Borrow(ctx context.Context, options ...BorrowOption) (*T, error)
+	Return(ctx context.Context, o *T)
+	Invalidate(ctx context.Context, o *T)
+	Stats() Stats
+}
+
+var _ Borrower[struct{}] = (*Pool[struct{}])(nil)
@@ -0,0 +1,150 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var errNoBudget = errors.New("shared budget exhausted")
+
+// budgetMember is a pool drawing objects from a shared budget
+type budgetMember interface {
+	Close(ctx context.Context)
+	shed() bool
+	wake()
+}
+
+// Budget is a capacity shared by several pools, each with its own size, minIdle and validation.
+// The total number of objects of all the pools never exceeds the budget size.
+// When a pool needs an object and the budget is exhausted, idle objects of the other pools are expired to make room.
+type Budget struct {
+	mutex   sync.Mutex
+	size    int
+	used    int
+	members []budgetMember
+	// sheds holds a pending request to expire an idle object, served by a single goroutine while shedding is set
+	sheds    chan struct{}
+	shedding bool
+}
+
+// NewBudget creates a budget for the given number of objects.
+func NewBudget(size int) *Budget {
+	if size < 1 {
+		size = 1
+	}
+	return &Budget{
+		size:  size,
+		sheds: make(chan struct{}, 1),
+	}
+}
+
+// SharedBudget makes the pool draw its objects from the budget.
+func SharedBudget[T any](budget *Budget) Option[T] {
+	return func(p *Pool[T]) {
+		p.budget = budget
+	}
+}
+
+// Close closes all the pools using the budget.
+func (b *Budget) Close(ctx context.Context) {
+	b.mutex.Lock()
+	members := b.members
+	b.members = nil
+	b.mutex.Unlock()
+
+	for _, m := range members {
+		m.Close(ctx)
+	}
+}
+
+func (b *Budget) attach(m budgetMember) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.members = append(b.members, m)
+}
+
+func (b *Budget) detach(m budgetMember) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i, member := range b.members {
+		if member == m {
+			b.members = append(b.members[:i:i], b.members[i+1:]...)
+			return
+		}
+	}
+}
+
+// acquire takes a unit of the budget, returning false if there is none available.
+// In that case, an idle object of one of the pools is expired in the background, by a single goroutine per budget.
+func (b *Budget) acquire() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.used < b.size {
+		b.used++
+		return true
+	}
+
+	select {
+	case b.sheds <- struct{}{}:
+	default:
+		// already requested
+	}
+	if !b.shedding {
+		b.shedding = true
+		go b.shed()
+	}
+
+	return false
+}
+
+// shed expires idle objects of the pools while there are requests to do so
+func (b *Budget) shed() {
+	for {
+		b.mutex.Lock()
+		select {
+		case <-b.sheds:
+		default:
+			b.shedding = false
+			b.mutex.Unlock()
+			return
+		}
+		members := append([]budgetMember(nil), b.members...)
+		b.mutex.Unlock()
+
+		for _, m := range members {
+			if m.shed() {
+				break
+			}
+		}
+	}
+}
+
+// release gives back a unit of the budget, waking up the pools waiting for it
+func (b *Budget) release() {
+	b.mutex.Lock()
+	if b.used > 0 {
+		b.used--
+	}
+	members := b.members
+	b.mutex.Unlock()
+
+	for _, m := range members {
+		m.wake()
+	}
+}
+
+// shed expires one idle object to give room to other pools sharing the budget
+func (p *Pool[T]) shed() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.evictIdle(context.Background())
+}
+
+func (p *Pool[T]) wake() {
+	p.cond.Broadcast()
+}
@@ -0,0 +1,109 @@
+package pool
+
+import (
+	"context"
+	"math/rand/v2"
+)
+
+// VariantStats are the stats of the objects built by one of the create functions
+type VariantStats struct {
+	// Objects is the number of existing objects
+	Objects int
+	// Created is the number of objects created
+	Created int
+	// CreateFailures is the number of failed creations
+	CreateFailures int
+	// Invalidated is the number of objects invalidated by the borrower or failing validation
+	Invalidated int
+}
+
+type canary[T any] struct {
+	create  func(context.Context) (*T, error)
+	weight  float64
+	objects map[*T]struct{}
+	// stats of the baseline and of the canary objects
+	baseline VariantStats
+	stats    VariantStats
+}
+
+// Canary builds a fraction of the new objects, given by weight between 0 and 1, with another create function,
+// to safely roll out a driver or configuration change. See CanaryStats.
+func Canary[T any](create func(context.Context) (*T, error), weight float64) Option[T] {
+	return func(p *Pool[T]) {
+		p.canary = &canary[T]{
+			create:  create,
+			weight:  weight,
+			objects: map[*T]struct{}{},
+		}
+	}
+}
+
+// CanaryStats returns the stats of the objects built by the main create function and by the canary one.
+func (p *Pool[T]) CanaryStats() (baseline, canary VariantStats) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var canaries map[*T]struct{}
+	if p.canary != nil {
+		baseline, canary = p.canary.baseline, p.canary.stats
+		canaries = p.canary.objects
+	}
+	// counted directly, since objects may also be quarantined or degraded
+	for _, objects := range []map[*T]*entry{p.unlocked, p.locked, p.quarantine, p.degraded} {
+		for o := range objects {
+			if _, ok := canaries[o]; ok {
+				canary.Objects++
+			} else {
+				baseline.Objects++
+			}
+		}
+	}
+	return baseline, canary
+}
+
+// variant picks the main or the canary create function, according to the canary weight, returning true for the canary
+func (p *Pool[T]) variant() (func(context.Context) (*T, error), bool) {
+	if p.canary == nil || rand.Float64() >= p.canary.weight {
+		return p.create, false
+	}
+	return p.canary.create, true
+}
+
+// createdVariant accounts for an object created by the main or the canary create function
+func (p *Pool[T]) createdVariant(o *T, err error, isCanary bool) {
+	if p.canary == nil {
+		return
+	}
+
+	stats := &p.canary.baseline
+	if isCanary {
+		stats = &p.canary.stats
+	}
+	if err != nil {
+		stats.CreateFailures++
+		return
+	}
+	stats.Created++
+	if isCanary {
+		p.canary.objects[o] = struct{}{}
+	}
+}
+
+// invalidated accounts for an object invalidated by the borrower or failing validation
+func (p *Pool[T]) invalidated(o *T) {
+	if p.canary == nil {
+		return
+	}
+	if _, ok := p.canary.objects[o]; ok {
+		p.canary.stats.Invalidated++
+	} else {
+		p.canary.baseline.Invalidated++
+	}
+}
+
+// forget removes an expired object from the canary objects
+func (p *Pool[T]) forget(o *T) {
+	if p.canary != nil {
+		delete(p.canary.objects, o)
+	}
+}
@@ -0,0 +1,57 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+type churn struct {
+	threshold int
+	alarm     func(ctx context.Context, churn int)
+	// count is the number of objects created and expired since the start of the minute
+	count int
+	since time.Time
+}
+
+// ChurnAlarm calls alarm, from the janitor, when the number of objects created and expired in a minute exceeds the threshold,
+// which hints at a too short idle timeout or at flapping validations.
+func ChurnAlarm[T any](threshold int, alarm func(ctx context.Context, churn int)) Option[T] {
+	return func(p *Pool[T]) {
+		p.churn = &churn{
+			threshold: threshold,
+			alarm:     alarm,
+		}
+	}
+}
+
+// churned accounts for an object created or expired
+func (p *Pool[T]) churned() {
+	if p.churn != nil {
+		p.churn.count++
+	}
+}
+
+// checkChurn calls the churn alarm once a minute, if the churn exceeded the threshold
+func (p *Pool[T]) checkChurn(ctx context.Context, now time.Time) {
+	p.mutex.Lock()
+	c := p.churn
+	if c == nil {
+		p.mutex.Unlock()
+		return
+	}
+	if c.since.IsZero() {
+		c.since = now
+	}
+	if now.Sub(c.since) < time.Minute {
+		p.mutex.Unlock()
+		return
+	}
+	n := c.count
+	c.count = 0
+	c.since = now
+	p.mutex.Unlock()
+
+	if n > c.threshold {
+		c.alarm(p.withInfo(ctx), n)
+	}
+}
@@ -0,0 +1,51 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+type cleanupsKey struct{}
+
+// cleanups are the functions registered with Cleanup during a creation
+type cleanups struct {
+	mutex sync.Mutex
+	funcs []func(context.Context)
+}
+
+// Cleanup registers a function to run when the object being created with the given context is expired, after the expire function.
+// It is meant to be called from the create function, to link the lifecycle of child resources, like a session of a connection, to the object.
+// The cleanups run in the reverse order of their registration, like defers, and they also run if the creation fails.
+// It does nothing if the context is not the one of a create call.
+func Cleanup(ctx context.Context, cleanup func(context.Context)) {
+	c, ok := ctx.Value(cleanupsKey{}).(*cleanups)
+	if !ok {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.funcs = append(c.funcs, cleanup)
+}
+
+// registered returns the registered cleanups
+func (c *cleanups) registered() []func(context.Context) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.funcs
+}
+
+// runCleanups runs the cleanups in the reverse order of their registration
+func runCleanups(ctx context.Context, funcs []func(context.Context)) {
+	for i := len(funcs) - 1; i >= 0; i-- {
+		funcs[i](ctx)
+	}
+}
+
+// takeCleanups removes the cleanups of an object, to run them when it is expired
+func (p *Pool[T]) takeCleanups(o *T) []func(context.Context) {
+	funcs := p.cleanups[o]
+	delete(p.cleanups, o)
+	return funcs
+}
@@ -5,10 +5,21 @@ import (
 	"sync"
 )
 
+// Cond is a condition variable that can be waited with a context.
+// Every Broadcast increments a sequence number, so that a waiter can detect
+// a Broadcast issued after it decided to wait but before it actually started waiting.
+//
+// Independently of Broadcast, Cond also hands out permits with SignalN,
+// each one consumed by exactly one WaitPermit.
 type Cond struct {
 	mutex  sync.Mutex
 	ch     chan struct{}
+	seq    uint64
 	closed bool
+	// permits not yet consumed by a waiter
+	permits int
+	// permit waiters, in arrival order
+	queue []chan struct{}
 }
 
 func NewCond() *Cond {
@@ -17,11 +28,31 @@ func NewCond() *Cond {
 	}
 }
 
+// Sequence returns the current sequence number, to be used with WaitSeq.
+func (s *Cond) Sequence() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.seq
+}
+
 // Wait waits for the condition to be signaled or for the context to be cancelled.
-// If the context is cancelled, it returns false. Otherwise, it returns true.
+// If the context is cancelled, it returns the context error.
 func (s *Cond) Wait(ctx context.Context) error {
+	return s.WaitSeq(ctx, s.Sequence())
+}
+
+// WaitSeq waits for a Broadcast issued after the sequence number was obtained or for the context to be cancelled.
+// If a Broadcast already happened since then, it returns immediately.
+// If the context is cancelled, it returns the context error.
+func (s *Cond) WaitSeq(ctx context.Context, seq uint64) error {
 	s.mutex.Lock()
 
+	if s.seq != seq {
+		s.mutex.Unlock()
+		return nil
+	}
+
 	if s.closed {
 		s.ch = make(chan struct{}, 0)
 		s.closed = false
@@ -43,6 +74,7 @@ func (s *Cond) Broadcast() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.seq++
 	if s.closed {
 		return
 	}
@@ -50,3 +82,71 @@ func (s *Cond) Broadcast() {
 	s.closed = true
 	close(s.ch)
 }
+
+// Signal is the same as SignalN(1).
+func (s *Cond) Signal() {
+	s.SignalN(1)
+}
+
+// SignalN releases n permits, waking up at most n goroutines waiting in WaitPermit, in arrival order.
+// Permits not consumed by a waiter are kept for future calls to WaitPermit.
+func (s *Cond) SignalN(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for ; n > 0; n-- {
+		s.release()
+	}
+}
+
+// release hands a permit to the first waiter or keeps it if there are no waiters
+func (s *Cond) release() {
+	if len(s.queue) == 0 {
+		s.permits++
+		return
+	}
+
+	close(s.queue[0])
+	s.queue = s.queue[1:]
+}
+
+// WaitPermit waits for a permit released by SignalN and consumes it, or for the context to be cancelled.
+// If the context is cancelled, no permit is consumed and it returns the context error.
+func (s *Cond) WaitPermit(ctx context.Context) error {
+	s.mutex.Lock()
+
+	if s.permits > 0 {
+		s.permits--
+		s.mutex.Unlock()
+		return nil
+	}
+
+	ch := make(chan struct{})
+	s.queue = append(s.queue, ch)
+
+	s.mutex.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-ch:
+		// a permit was handed to us in the meantime, so we give it back
+		s.release()
+	default:
+		for i, c := range s.queue {
+			if c == ch {
+				s.queue = append(s.queue[:i], s.queue[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return ctx.Err()
+}
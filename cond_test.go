@@ -63,3 +63,107 @@ func TestMultipleWait(t *testing.T) {
 
 	assert.Equal(t, int32(2), count.Load())
 }
+
+func TestWaitSeqAfterBroadcast(t *testing.T) {
+	cond := pool.NewCond()
+
+	seq := cond.Sequence()
+	cond.Broadcast()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// the broadcast happened after the sequence was taken, so it is not lost
+	err := cond.WaitSeq(ctx, seq)
+	require.NoError(t, err)
+
+	err = cond.WaitSeq(ctx, cond.Sequence())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSignalN(t *testing.T) {
+	cond := pool.NewCond()
+
+	count := atomic.Int32{}
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if cond.WaitPermit(ctx) == nil {
+				count.Add(1)
+			}
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	cond.SignalN(2)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(2), count.Load())
+
+	cancel()
+	wg.Wait()
+	assert.Equal(t, int32(2), count.Load())
+
+	// permits without waiters are kept
+	cond.Signal()
+	err := cond.WaitPermit(context.Background())
+	require.NoError(t, err)
+}
+
+func TestSpinCond(t *testing.T) {
+	cond := pool.NewSpinCond(10 * time.Millisecond)
+
+	// woken up while spinning
+	seq := cond.Sequence()
+	go cond.Broadcast()
+	require.NoError(t, cond.WaitSeq(context.Background(), seq))
+
+	// woken up after parking
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cond.Broadcast()
+	}()
+	require.NoError(t, cond.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, cond.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestWaitStrategies(t *testing.T) {
+	strategies := map[string]func() pool.WaitStrategy{
+		"handoff":   func() pool.WaitStrategy { return pool.NewHandoffCond() },
+		"semaphore": func() pool.WaitStrategy { return pool.NewSemaCond() },
+	}
+	for name, strategy := range strategies {
+		t.Run(name, func(t *testing.T) {
+			cond := strategy()
+
+			// the broadcast happened after the sequence was taken, so it is not lost
+			seq := cond.Sequence()
+			cond.Broadcast()
+			require.NoError(t, cond.WaitSeq(context.Background(), seq))
+
+			var wg sync.WaitGroup
+			count := atomic.Int32{}
+			for range 3 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					require.NoError(t, cond.WaitSeq(context.Background(), seq+1))
+					count.Add(1)
+				}()
+			}
+			time.Sleep(50 * time.Millisecond)
+			cond.Broadcast()
+			wg.Wait()
+			assert.Equal(t, int32(3), count.Load())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			require.ErrorIs(t, cond.WaitSeq(ctx, cond.Sequence()), context.DeadlineExceeded)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config is the configuration resulting from the pool options
+type Config struct {
+	// Size is zero if unbounded
+	Size          int
+	MinIdle       int
+	JanitorSleep  time.Duration
+	IdleTimeout   time.Duration
+	BorrowTimeout time.Duration
+	MaxLifetime   time.Duration
+	ExpireTimeout time.Duration
+	// Reserved is the total capacity reserved to borrower classes
+	Reserved int
+	// AutoScaling is nil if the pool is not auto scaled
+	AutoScaling *AutoScaling
+}
+
+// NewConfig returns the configuration resulting from applying the options to the defaults,
+// so that it can be checked before creating the pool.
+func NewConfig[T any](options ...Option[T]) Config {
+	return newPool[T](nil, nil, options).config()
+}
+
+func (p *Pool[T]) config() Config {
+	reserved := 0
+	for _, n := range p.reservations {
+		reserved += n
+	}
+	return Config{
+		Size:          p.capacity(),
+		MinIdle:       p.minIdle,
+		JanitorSleep:  p.janitorSleep,
+		IdleTimeout:   p.idleTimeout,
+		BorrowTimeout: p.borrowTimeout,
+		MaxLifetime:   p.maxLifetime,
+		ExpireTimeout: p.expireTimeout,
+		Reserved:      reserved,
+		AutoScaling:   p.autoScaling,
+	}
+}
+
+// Validate checks that the configuration is consistent, describing every problem found.
+func (c Config) Validate() error {
+	var problems []string
+	if c.Size < 0 {
+		problems = append(problems, fmt.Sprintf("size %d must not be negative", c.Size))
+	}
+	if c.Size > 0 && c.MinIdle > c.Size {
+		problems = append(problems, fmt.Sprintf("min idle %d is greater than the size %d", c.MinIdle, c.Size))
+	}
+	if c.JanitorSleep <= 0 {
+		problems = append(problems, fmt.Sprintf("janitor sleep %s must be positive", c.JanitorSleep))
+	}
+	if c.IdleTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("idle timeout %s must be positive", c.IdleTimeout))
+	} else if c.JanitorSleep >= c.IdleTimeout {
+		problems = append(problems, fmt.Sprintf("janitor sleep %s must be less than the idle timeout %s", c.JanitorSleep, c.IdleTimeout))
+	}
+	if c.BorrowTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("borrow timeout %s must be positive", c.BorrowTimeout))
+	}
+	if c.MaxLifetime < 0 {
+		problems = append(problems, fmt.Sprintf("max lifetime %s must not be negative", c.MaxLifetime))
+	}
+	if c.ExpireTimeout < 0 {
+		problems = append(problems, fmt.Sprintf("expire timeout %s must not be negative", c.ExpireTimeout))
+	}
+	if c.Size > 0 && c.Reserved > c.Size {
+		problems = append(problems, fmt.Sprintf("reserved capacity %d is greater than the size %d", c.Reserved, c.Size))
+	}
+	if c.AutoScaling != nil && c.MinIdle > c.AutoScaling.Min {
+		problems = append(problems, fmt.Sprintf("min idle %d is greater than the auto scaling min %d", c.MinIdle, c.AutoScaling.Min))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DeepValidate sets a slower validation, like a protocol level health query, run by the janitor on the idle objects.
+// Each object is taken out of the idle set while being validated, so borrowers are not blocked by it.
+func DeepValidate[T any](deepValidate func(context.Context, *T) (bool, error)) Option[T] {
+	return func(p *Pool[T]) {
+		p.deepValidate = deepValidate
+	}
+}
+
+// deepValidateIdle runs the deep validation on the objects that are idle,
+// expiring the ones that are not valid
+func (p *Pool[T]) deepValidateIdle(ctx context.Context) error {
+	if p.deepValidate == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	idle := make([]*T, 0, len(p.unlocked))
+	for o := range p.unlocked {
+		idle = append(idle, o)
+	}
+	p.mutex.Unlock()
+
+	for _, o := range idle {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		p.mutex.Lock()
+		e, ok := p.unlocked[o]
+		if !ok || p.closed {
+			// borrowed or expired in the meantime
+			p.mutex.Unlock()
+			continue
+		}
+		delete(p.unlocked, o)
+		e.since = time.Now()
+		p.locked[o] = e
+		p.mutex.Unlock()
+
+		valid, err := p.deepValidate(p.callbackContext(ctx), o)
+
+		p.mutex.Lock()
+		if err != nil {
+			p.failed(err)
+		}
+		if err != nil || valid {
+			p.release(ctx, o)
+		} else {
+			delete(p.locked, o)
+			p.invalidated(o)
+			if !p.quarantined(o, e, time.Now()) {
+				p.destroy(ctx, o)
+			}
+			p.signal()
+		}
+		p.mutex.Unlock()
+
+		if err != nil {
+			return fmt.Errorf("on deep validation: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+)
+
+// Dependency is a pool that other pools can depend on, see DependsOn
+type Dependency interface {
+	addDependent(d dependent) error
+	removeDependent(d dependent)
+}
+
+// dependent is a pool depending on another one
+type dependent interface {
+	Close(ctx context.Context)
+}
+
+// DependsOn declares that the objects of the pool depend on the objects of the given pools, like when embedding them,
+// so that closing any of those pools first closes this one, never leaving objects using closed resources.
+func DependsOn[T any](parents ...Dependency) Option[T] {
+	return func(p *Pool[T]) {
+		p.parents = append(p.parents, parents...)
+	}
+}
+
+func (p *Pool[T]) addDependent(d dependent) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("on adding a dependent pool: %w", ErrPoolClosed)
+	}
+	p.dependents = append(p.dependents, d)
+	return nil
+}
+
+func (p *Pool[T]) removeDependent(d dependent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i, dep := range p.dependents {
+		if dep == d {
+			p.dependents = append(p.dependents[:i:i], p.dependents[i+1:]...)
+			return
+		}
+	}
+}
+
+// depend registers the pool as a dependent of its parents
+func (p *Pool[T]) depend() error {
+	for i, parent := range p.parents {
+		if err := parent.addDependent(p); err != nil {
+			for _, prev := range p.parents[:i] {
+				prev.removeDependent(p)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// closeDependents closes the pools depending on this one, before its objects are expired
+func (p *Pool[T]) closeDependents(ctx context.Context) {
+	p.mutex.Lock()
+	dependents := p.dependents
+	p.dependents = nil
+	p.mutex.Unlock()
+
+	for _, d := range dependents {
+		d.Close(ctx)
+	}
+	for _, parent := range p.parents {
+		parent.removeDependent(p)
+	}
+}
@@ -0,0 +1,52 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Each borrows up to n objects, as many as are available without waiting but at least one, and calls fn concurrently with each of them.
+// Like an errgroup, the context passed to fn is cancelled on the first error, which is the one returned.
+// Each object is returned, or invalidated if fn returns an error wrapping ErrInvalidObject, as with Use.
+func Each[T any](ctx context.Context, p Borrower[T], n int, fn func(context.Context, *T) error) error {
+	first, err := p.Borrow(ctx)
+	if err != nil {
+		return fmt.Errorf("on each: %w", err)
+	}
+	objects := []*T{first}
+	for len(objects) < n {
+		o, err := p.Borrow(ctx, WithNoWait())
+		if err != nil {
+			break
+		}
+		objects = append(objects, o)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, o := range objects {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := call(ctx, p, o, func() error {
+				return fn(ctx, o)
+			})
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel(err)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,89 @@
+package pool
+
+import "time"
+
+// eventsBuffer is the number of events kept for a slow consumer
+const eventsBuffer = 256
+
+type EventType int
+
+const (
+	EventCreated EventType = iota + 1
+	EventCreateFailed
+	EventBorrowed
+	EventReturned
+	EventEvicted
+	EventReclaimed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCreated:
+		return "created"
+	case EventCreateFailed:
+		return "create-failed"
+	case EventBorrowed:
+		return "borrowed"
+	case EventReturned:
+		return "returned"
+	case EventEvicted:
+		return "evicted"
+	case EventReclaimed:
+		return "reclaimed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is something that happened in the pool
+type Event struct {
+	Type EventType
+	Time time.Time
+	// Object identifies the object, if the event is about one, see Identity
+	Object string
+	// Err is the error of a failed creation
+	Err error
+}
+
+// Events returns a channel with the events of the pool, starting from the first call.
+// When the consumer falls behind, the oldest events are dropped.
+// The channel is closed when the pool is closed.
+func (p *Pool[T]) Events() <-chan Event {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.events == nil {
+		p.events = make(chan Event, eventsBuffer)
+		if p.closed {
+			close(p.events)
+		}
+	}
+	return p.events
+}
+
+// emit publishes an event, dropping the oldest one if the buffer is full, and records it in the journal
+func (p *Pool[T]) emit(t EventType, o *T, err error) {
+	if (p.events == nil && p.journal == nil) || p.closed {
+		return
+	}
+
+	event := Event{Type: t, Time: time.Now(), Err: err}
+	if o != nil {
+		event.Object = p.identify(o)
+	}
+	p.record(event)
+	if p.events == nil {
+		return
+	}
+	for {
+		select {
+		case p.events <- event:
+			return
+		default:
+		}
+		select {
+		case <-p.events:
+		default:
+		}
+	}
+}
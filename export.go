@@ -0,0 +1,92 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Export takes the idle objects out of the pool, without expiring them, returning them encoded,
+// so that another process can Import them, for objects that are tokens or handles rather than sockets.
+// Objects that fail to be encoded are kept in the pool. The cleanups of the exported objects are run.
+// It is meant to be called right before closing the pool, since the idle objects are not replenished.
+func (p *Pool[T]) Export(ctx context.Context, encode func(*T) ([]byte, error)) ([][]byte, error) {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil, fmt.Errorf("on export: %w", ErrPoolClosed)
+	}
+	var (
+		data     [][]byte
+		cleanups [][]func(context.Context)
+		errs     []error
+	)
+	for o := range p.unlocked {
+		b, err := encode(o)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(p.unlocked, o)
+		p.unbind(o)
+		p.forget(o)
+		if p.budget != nil {
+			p.budget.release()
+		}
+		data = append(data, b)
+		cleanups = append(cleanups, p.takeCleanups(o))
+	}
+	p.drained()
+	p.mutex.Unlock()
+
+	ctx = p.callbackContext(ctx)
+	for _, funcs := range cleanups {
+		runCleanups(ctx, funcs)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return data, fmt.Errorf("on export: %w", err)
+	}
+	return data, nil
+}
+
+// Import adds the objects exported by another pool as idle objects, returning how many were added.
+// Objects are only added while there is room for them, and the ones that do not fit are expired.
+func (p *Pool[T]) Import(ctx context.Context, data [][]byte, decode func(context.Context, []byte) (*T, error)) (int, error) {
+	var (
+		objects []*T
+		errs    []error
+	)
+	cbCtx := p.callbackContext(ctx)
+	for _, b := range data {
+		o, err := decode(cbCtx, b)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		objects = append(objects, o)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	imported := 0
+	for _, o := range objects {
+		if p.closed || p.softClosed || p.objectCount() >= p.size || (p.budget != nil && !p.budget.acquire()) {
+			p.expireUnbudgeted(ctx, o, nil)
+			continue
+		}
+		p.unlocked[o] = p.newEntry()
+		imported++
+	}
+	p.trackPeaks()
+	p.signal()
+
+	if p.closed {
+		errs = append(errs, ErrPoolClosed)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return imported, fmt.Errorf("on import: %w", err)
+	}
+	return imported, nil
+}
@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FailoverPolicy configures when a failing create function is demoted
+type FailoverPolicy struct {
+	// Failures is the number of consecutive failures after which a create function is demoted. Defaults to 1.
+	Failures int
+	// Cooldown is how long a demoted create function is skipped before being tried again
+	Cooldown time.Duration
+}
+
+type factory[T any] struct {
+	index        int
+	create       func(context.Context) (*T, error)
+	failures     int
+	demotedUntil time.Time
+}
+
+// Failover returns a create function that tries the given create functions in order, like primary and replicas.
+// A create function failing repeatedly is demoted and only tried again after the cooldown, being promoted back on success.
+// Demoted create functions are still tried as a last resort.
+func Failover[T any](policy FailoverPolicy, creates ...func(context.Context) (*T, error)) func(context.Context) (*T, error) {
+	if policy.Failures < 1 {
+		policy.Failures = 1
+	}
+	var mutex sync.Mutex
+	factories := make([]*factory[T], len(creates))
+	for i, create := range creates {
+		factories[i] = &factory[T]{index: i, create: create}
+	}
+
+	return func(ctx context.Context) (*T, error) {
+		mutex.Lock()
+		now := time.Now()
+		healthy := make([]*factory[T], 0, len(factories))
+		var demoted []*factory[T]
+		for _, f := range factories {
+			if now.Before(f.demotedUntil) {
+				demoted = append(demoted, f)
+			} else {
+				healthy = append(healthy, f)
+			}
+		}
+		mutex.Unlock()
+
+		var errs []error
+		for _, f := range append(healthy, demoted...) {
+			o, err := f.create(ctx)
+
+			mutex.Lock()
+			if err == nil {
+				f.failures = 0
+				f.demotedUntil = time.Time{}
+			} else {
+				f.failures++
+				if f.failures >= policy.Failures {
+					f.demotedUntil = time.Now().Add(policy.Cooldown)
+				}
+			}
+			mutex.Unlock()
+
+			if err == nil {
+				return o, nil
+			}
+			errs = append(errs, fmt.Errorf("on create function %d: %w", f.index, err))
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		return nil, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,38 @@
+package pool
+
+// ErrMaintenance is the error of the borrows on a frozen pool, see Freeze
+type ErrMaintenance struct {
+	Reason string
+}
+
+func (e ErrMaintenance) Error() string {
+	return "pool is under maintenance: " + e.Reason
+}
+
+// Freeze makes the borrows fail fast with ErrMaintenance, including the ones already waiting, until Unfreeze is called.
+// Returns still work, so that a node can be drained deterministically.
+func (p *Pool[T]) Freeze(reason string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.frozen = true
+	p.freezeReason = reason
+	p.signal()
+}
+
+// Unfreeze lets the borrows succeed again after Freeze.
+func (p *Pool[T]) Unfreeze() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.frozen = false
+	p.freezeReason = ""
+}
+
+// maintenance returns ErrMaintenance if the pool is frozen
+func (p *Pool[T]) maintenance() error {
+	if !p.frozen {
+		return nil
+	}
+	return ErrMaintenance{Reason: p.freezeReason}
+}
@@ -2,7 +2,10 @@ module github.com/quintans/pool
 
 go 1.22.5
 
-require github.com/stretchr/testify v1.9.0
+require (
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/time v0.10.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -0,0 +1,50 @@
+package pool
+
+import "time"
+
+type failFast struct {
+	failures int
+	cooldown time.Duration
+	// consecutive is the number of consecutive creation failures, and failedAt when the last one happened
+	consecutive int
+	failedAt    time.Time
+}
+
+// FailFast marks the pool as unhealthy after the given number of consecutive creation failures.
+// While unhealthy, borrows that would create an object fail immediately with ErrUnhealthy,
+// until the cooldown since the last failure has passed, when a creation is tried again.
+func FailFast[T any](failures int, cooldown time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.failFast = &failFast{
+			failures: max(failures, 1),
+			cooldown: cooldown,
+		}
+	}
+}
+
+// Healthy returns false if the pool is failing fast after repeated creation failures, see FailFast.
+func (p *Pool[T]) Healthy() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.healthy(time.Now())
+}
+
+func (p *Pool[T]) healthy(now time.Time) bool {
+	ff := p.failFast
+	return ff == nil || ff.consecutive < ff.failures || now.Sub(ff.failedAt) >= ff.cooldown
+}
+
+// created accounts for the outcome of a creation, for the health of the pool
+func (p *Pool[T]) created(err error) {
+	ff := p.failFast
+	if ff == nil {
+		return
+	}
+	if err == nil {
+		ff.consecutive = 0
+		return
+	}
+	ff.consecutive++
+	ff.failedAt = time.Now()
+}
@@ -0,0 +1,40 @@
+package pool
+
+import "time"
+
+// StatsSample is the stats of the pool at a point in time, see StatsHistory
+type StatsSample struct {
+	Time  time.Time
+	Stats Stats
+}
+
+// StatsHistory samples the stats on every janitor run, keeping the last n samples, see History.
+// It allows simple dashboards and post-incident analysis without an external metrics system.
+func StatsHistory[T any](n int) Option[T] {
+	return func(p *Pool[T]) {
+		if n < 1 {
+			p.history = nil
+			return
+		}
+		p.history = newRing[StatsSample](n)
+	}
+}
+
+// History returns the sampled stats, from the oldest to the newest, see StatsHistory.
+func (p *Pool[T]) History() []StatsSample {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.history.snapshot()
+}
+
+// sample keeps the current stats in the history
+func (p *Pool[T]) sample(now time.Time) {
+	if p.history == nil {
+		return
+	}
+	stats := p.stats()
+	// the journal is already a history
+	stats.Journal = nil
+	p.history.add(StatsSample{Time: now, Stats: stats})
+}
@@ -0,0 +1,20 @@
+package pool
+
+import "fmt"
+
+// Identity sets how objects are identified in logs, events and Inspect, like a connection ID or a remote address,
+// to correlate them with other logs. By default objects are identified by their address.
+// It is called while holding the pool lock, so it must be cheap.
+func Identity[T any](id func(*T) string) Option[T] {
+	return func(p *Pool[T]) {
+		p.identity = id
+	}
+}
+
+// identify returns the identity of the object
+func (p *Pool[T]) identify(o *T) string {
+	if p.identity != nil {
+		return p.identity(o)
+	}
+	return fmt.Sprintf("%p", o)
+}
@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var errCreateGrace = errors.New("borrower gave up and the create grace expired")
+
+// CreateGrace is how long the creation of an object for a borrower may go on after the borrower gives up,
+// so that the object is kept idle, before its context is cancelled. Defaults to 10s.
+// Without it, a create that hangs would hold its slot in the pool forever.
+func CreateGrace[T any](grace time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.createGrace = grace
+	}
+}
+
+// inflight is an object being created for a borrower without holding the lock
+type inflight[T any] struct {
+	done chan struct{}
+	o    *T
+	err  error
+	// abandoned is true if the borrower gave up waiting for the object
+	abandoned bool
+}
+
+// createForBorrow creates an object without holding the lock, so that a slow create does not block the pool.
+// If the borrower gives up before the object is created, the object is parked idle instead of being lost,
+// if it is created within the create grace.
+// It must be called while holding the lock.
+func (p *Pool[T]) createForBorrow(ctx context.Context, class string) (*T, error) {
+	c, err := p.startCreation()
+	if err != nil {
+		return nil, err
+	}
+
+	// the creation outlives the borrower, up to the create grace
+	createCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	f := &inflight[T]{done: make(chan struct{})}
+	p.creatingFor(class, 1)
+	p.background.Add(1)
+	go func() {
+		defer p.background.Done()
+		defer cancel(nil)
+		o, err := c.run(p.callbackContext(createCtx))
+
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		f.o, f.err = p.endCreation(ctx, c, o, err)
+		if f.abandoned {
+			p.creatingFor(class, -1)
+			p.park(ctx, f.o)
+		}
+		close(f.done)
+	}()
+
+	p.mutex.Unlock()
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+	}
+	p.mutex.Lock()
+
+	select {
+	case <-f.done:
+	default:
+		f.abandoned = true
+		time.AfterFunc(p.createGrace, func() {
+			cancel(errCreateGrace)
+		})
+		return nil, ctx.Err()
+	}
+
+	p.creatingFor(class, -1)
+	if f.err != nil {
+		// there is room for another object
+		p.signal()
+		p.drained()
+		return nil, f.err
+	}
+	if p.closed {
+		p.destroy(ctx, f.o)
+		return nil, ErrPoolClosed
+	}
+	return f.o, nil
+}
+
+// park keeps an object created for a borrower that gave up waiting, as an idle object
+func (p *Pool[T]) park(ctx context.Context, o *T) {
+	if o == nil {
+		// the creation failed, so there is room for another object
+		p.signal()
+		p.drained()
+		return
+	}
+	if p.closed || p.objectCount() >= p.size {
+		p.destroy(ctx, o)
+		return
+	}
+	p.unlocked[o] = p.newEntry()
+	p.trackPeaks()
+	p.signal()
+}
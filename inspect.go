@@ -0,0 +1,151 @@
+package pool
+
+import (
+	"sort"
+	"time"
+)
+
+type State int
+
+const (
+	StateUnknown State = iota
+	StateIdle
+	StateBorrowed
+	// StateQuarantined is an object that failed validation, waiting to be rescued, see Quarantine
+	StateQuarantined
+	// StateDegraded is an object that failed validation, kept to be served stale, see ServeStale
+	StateDegraded
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateBorrowed:
+		return "borrowed"
+	case StateQuarantined:
+		return "quarantined"
+	case StateDegraded:
+		return "degraded"
+	default:
+		return "unknown"
+	}
+}
+
+// ObjectInfo describes an object managed by the pool
+type ObjectInfo[T any] struct {
+	Object         *T
+	State          State
+	CreatedAt      time.Time
+	LastBorrowedAt time.Time
+	// IdleSince is zero if the object is borrowed
+	IdleSince time.Time
+	Borrows   int
+	// Label is the label of the current borrower
+	Label string
+	// ID identifies the object, see Identity
+	ID string
+}
+
+// Inspect returns the description of every object managed by the pool
+func (p *Pool[T]) Inspect() []ObjectInfo[T] {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	infos := make([]ObjectInfo[T], 0, p.objectCount())
+	for _, set := range []struct {
+		objects map[*T]*entry
+		state   State
+	}{{p.unlocked, StateIdle}, {p.quarantine, StateQuarantined}, {p.degraded, StateDegraded}} {
+		for o, e := range set.objects {
+			infos = append(infos, ObjectInfo[T]{
+				Object:         o,
+				ID:             p.identify(o),
+				State:          set.state,
+				CreatedAt:      e.createdAt,
+				LastBorrowedAt: e.lastBorrowed,
+				IdleSince:      e.since,
+				Borrows:        e.borrows,
+			})
+		}
+	}
+	for o, e := range p.locked {
+		infos = append(infos, ObjectInfo[T]{
+			Object:         o,
+			ID:             p.identify(o),
+			State:          StateBorrowed,
+			CreatedAt:      e.createdAt,
+			LastBorrowedAt: e.lastBorrowed,
+			Borrows:        e.borrows,
+			Label:          e.label,
+		})
+	}
+
+	return infos
+}
+
+// StateOf returns the state of the object in the pool.
+// StateUnknown means that the object does not belong to the pool or was expired.
+func (p *Pool[T]) StateOf(o *T) State {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.unlocked[o]; ok {
+		return StateIdle
+	}
+	if _, ok := p.locked[o]; ok {
+		return StateBorrowed
+	}
+	if _, ok := p.quarantine[o]; ok {
+		return StateQuarantined
+	}
+	if _, ok := p.degraded[o]; ok {
+		return StateDegraded
+	}
+	return StateUnknown
+}
+
+// Holder describes a borrowed object and who holds it
+type Holder[T any] struct {
+	Object *T
+	// ID identifies the object, see Identity
+	ID string
+	// Label is the label of the borrower
+	Label string
+	// HeldFor is how long the object has been borrowed
+	HeldFor time.Duration
+	// Stack is where the object was borrowed, if DebugStacks is set
+	Stack string
+}
+
+// DebugStacks records the stack of every borrow, to be reported by LongestHolders,
+// and reports a borrower waiting for an object while holding another one, since it may wait for itself.
+// It is expensive, so it is meant for debugging.
+func DebugStacks[T any]() Option[T] {
+	return func(p *Pool[T]) {
+		p.debugStacks = true
+	}
+}
+
+// LongestHolders returns up to n borrowed objects that have been held the longest, longest first.
+func (p *Pool[T]) LongestHolders(n int) []Holder[T] {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	holders := make([]Holder[T], 0, len(p.locked))
+	for o, e := range p.locked {
+		holders = append(holders, Holder[T]{
+			Object:  o,
+			ID:      p.identify(o),
+			Label:   e.label,
+			HeldFor: now.Sub(e.lastBorrowed),
+			Stack:   e.stack,
+		})
+	}
+	sort.Slice(holders, func(i, j int) bool {
+		return holders[i].HeldFor > holders[j].HeldFor
+	})
+
+	return holders[:min(n, len(holders))]
+}
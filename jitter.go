@@ -0,0 +1,22 @@
+package pool
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// ExpiryJitter adds a random extra time, up to d, to the idle timeout and to the max lifetime of each object,
+// so that objects created together, like on warmup, do not all expire in the same sweep.
+func ExpiryJitter[T any](d time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.expiryJitter = d
+	}
+}
+
+// jitter returns the random extra time before a new object expires, see ExpiryJitter
+func (p *Pool[T]) jitter() time.Duration {
+	if p.expiryJitter <= 0 {
+		return 0
+	}
+	return rand.N(p.expiryJitter)
+}
@@ -0,0 +1,26 @@
+package pool
+
+// Journal keeps the last n lifecycle events, creations, creation failures, evictions and reclaims, in memory,
+// so that they can be looked at after an incident, in the stats, even when the logs were sampled away.
+func Journal[T any](n int) Option[T] {
+	return func(p *Pool[T]) {
+		if n < 1 {
+			p.journal = nil
+			return
+		}
+		p.journal = newRing[Event](n)
+	}
+}
+
+// record keeps a lifecycle event in the journal
+func (p *Pool[T]) record(event Event) {
+	if p.journal == nil {
+		return
+	}
+	switch event.Type {
+	case EventBorrowed, EventReturned:
+		// not lifecycle events
+		return
+	}
+	p.journal.add(event)
+}
@@ -0,0 +1,273 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type KeyedOption[K comparable, T any] func(*KeyedPool[K, T])
+
+// PerKey sets the options of the pool of each key.
+func PerKey[K comparable, T any](options ...Option[T]) KeyedOption[K, T] {
+	return func(kp *KeyedPool[K, T]) {
+		kp.options = append(kp.options, options...)
+	}
+}
+
+// MaxPerKey sets the maximum number of objects of each key.
+func MaxPerKey[K comparable, T any](n int) KeyedOption[K, T] {
+	return func(kp *KeyedPool[K, T]) {
+		kp.options = append(kp.options, Size[T](n))
+	}
+}
+
+// MaxTotal sets the maximum number of objects across all keys.
+func MaxTotal[K comparable, T any](n int) KeyedOption[K, T] {
+	return func(kp *KeyedPool[K, T]) {
+		kp.budget = NewBudget(n)
+	}
+}
+
+// MaxKeys sets the maximum number of keys. When a new key exceeds it,
+// the least recently used key without borrowed objects is evicted, expiring all its objects.
+func MaxKeys[K comparable, T any](n int) KeyedOption[K, T] {
+	return func(kp *KeyedPool[K, T]) {
+		kp.maxKeys = n
+	}
+}
+
+// KeyIdleTimeout sets how long a key without borrowed objects can go unused before being evicted.
+func KeyIdleTimeout[K comparable, T any](d time.Duration) KeyedOption[K, T] {
+	return func(kp *KeyedPool[K, T]) {
+		kp.keyIdleTimeout = d
+	}
+}
+
+type keyedEntry[T any] struct {
+	pool     *Pool[T]
+	lastUsed time.Time
+	// borrowers is the number of borrows in progress, which keep the key from being evicted
+	borrowers int
+}
+
+// KeyedPool keeps a pool of objects for each key, like a tenant or an endpoint.
+type KeyedPool[K comparable, T any] struct {
+	mutex          sync.Mutex
+	ctx            context.Context
+	cancel         context.CancelFunc
+	create         func(context.Context, K) (*T, error)
+	expire         func(context.Context, *T)
+	options        []Option[T]
+	budget         *Budget
+	maxKeys        int
+	keyIdleTimeout time.Duration
+	pools          map[K]*keyedEntry[T]
+	closed         bool
+}
+
+// NewKeyed creates a keyed pool, where create builds the objects for a key.
+// The pool of each key is created on its first borrow.
+func NewKeyed[K comparable, T any](
+	ctx context.Context,
+	create func(context.Context, K) (*T, error),
+	expire func(context.Context, *T),
+	options ...KeyedOption[K, T],
+) *KeyedPool[K, T] {
+	kp := &KeyedPool[K, T]{
+		create: create,
+		expire: expire,
+		pools:  map[K]*keyedEntry[T]{},
+	}
+	for _, opt := range options {
+		opt(kp)
+	}
+
+	ctx, kp.cancel = context.WithCancel(ctx)
+	kp.ctx = ctx
+	if kp.keyIdleTimeout > 0 {
+		go func() {
+			ticker := time.NewTicker(kp.keyIdleTimeout / 2)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					kp.Close(context.WithoutCancel(ctx))
+					return
+				case <-ticker.C:
+					kp.evictIdleKeys(ctx)
+				}
+			}
+		}()
+	}
+
+	return kp
+}
+
+// Pool returns the pool of the key, creating it if needed.
+// The pool is closed if the key is evicted, so Borrow should be preferred.
+func (kp *KeyedPool[K, T]) Pool(key K) (*Pool[T], error) {
+	e, err := kp.entry(key, false)
+	if err != nil {
+		return nil, err
+	}
+	return e.pool, nil
+}
+
+// entry returns the entry of the key, creating it if needed.
+// If borrowing, the key is kept from being evicted until the borrow is done.
+func (kp *KeyedPool[K, T]) entry(key K, borrowing bool) (*keyedEntry[T], error) {
+	kp.mutex.Lock()
+	var victim *Pool[T]
+	defer func() {
+		kp.mutex.Unlock()
+		// closing waits for the janitor, so it is done without holding the lock
+		if victim != nil {
+			victim.Close(kp.ctx)
+		}
+	}()
+
+	if kp.closed {
+		return nil, fmt.Errorf("on keyed pool: %w", ErrPoolClosed)
+	}
+
+	e, ok := kp.pools[key]
+	if !ok {
+		if kp.maxKeys > 0 && len(kp.pools) >= kp.maxKeys {
+			victim = kp.evictLRU()
+			if victim == nil {
+				return nil, fmt.Errorf("on keyed pool: all %d keys are in use: %w", kp.maxKeys, ErrPoolExhausted)
+			}
+		}
+
+		options := append([]Option[T]{Register[T](nil)}, kp.options...)
+		if kp.budget != nil {
+			options = append(options, SharedBudget[T](kp.budget))
+		}
+		create := func(ctx context.Context) (*T, error) {
+			return kp.create(ctx, key)
+		}
+		p, err := New(kp.ctx, create, kp.expire, options...)
+		if err != nil {
+			return nil, fmt.Errorf("on creating pool for key %v: %w", key, err)
+		}
+		e = &keyedEntry[T]{pool: p}
+		kp.pools[key] = e
+	}
+
+	e.lastUsed = time.Now()
+	if borrowing {
+		e.borrowers++
+	}
+	return e, nil
+}
+
+func (kp *KeyedPool[K, T]) Borrow(ctx context.Context, key K, options ...BorrowOption) (*T, error) {
+	e, err := kp.entry(key, true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		kp.mutex.Lock()
+		e.borrowers--
+		kp.mutex.Unlock()
+	}()
+	return e.pool.Borrow(ctx, options...)
+}
+
+// Return returns the object to the pool of the key. If the key was evicted, the object is expired.
+func (kp *KeyedPool[K, T]) Return(ctx context.Context, key K, o *T) {
+	if p := kp.lookup(key); p != nil {
+		p.Return(ctx, o)
+		return
+	}
+	kp.expire(ctx, o)
+}
+
+// Invalidate expires the object, borrowed from the pool of the key.
+func (kp *KeyedPool[K, T]) Invalidate(ctx context.Context, key K, o *T) {
+	if p := kp.lookup(key); p != nil {
+		p.Invalidate(ctx, o)
+		return
+	}
+	kp.expire(ctx, o)
+}
+
+func (kp *KeyedPool[K, T]) lookup(key K) *Pool[T] {
+	kp.mutex.Lock()
+	defer kp.mutex.Unlock()
+
+	if e, ok := kp.pools[key]; ok {
+		return e.pool
+	}
+	return nil
+}
+
+// Keys returns the number of keys with a pool.
+func (kp *KeyedPool[K, T]) Keys() int {
+	kp.mutex.Lock()
+	defer kp.mutex.Unlock()
+
+	return len(kp.pools)
+}
+
+// Close closes the pools of all keys, expiring their idle objects with the given context.
+func (kp *KeyedPool[K, T]) Close(ctx context.Context) {
+	kp.mutex.Lock()
+	pools := kp.pools
+	kp.pools = map[K]*keyedEntry[T]{}
+	kp.closed = true
+	kp.mutex.Unlock()
+
+	// close before cancelling, so that the janitors of the pools do not expire the idle objects with the cancelled context
+	for _, e := range pools {
+		e.pool.Close(ctx)
+	}
+	kp.cancel()
+}
+
+// evictLRU removes the least recently used key without borrowed objects, returning its pool to be closed, or nil if there is none
+func (kp *KeyedPool[K, T]) evictLRU() *Pool[T] {
+	var (
+		lru   K
+		found *keyedEntry[T]
+	)
+	for k, e := range kp.pools {
+		if !e.evictable() {
+			continue
+		}
+		if found == nil || e.lastUsed.Before(found.lastUsed) {
+			lru, found = k, e
+		}
+	}
+	if found == nil {
+		return nil
+	}
+
+	delete(kp.pools, lru)
+	return found.pool
+}
+
+// evictIdleKeys closes the keys without borrowed objects not used within the key idle timeout
+func (kp *KeyedPool[K, T]) evictIdleKeys(ctx context.Context) {
+	kp.mutex.Lock()
+	var victims []*Pool[T]
+	now := time.Now()
+	for k, e := range kp.pools {
+		if now.Sub(e.lastUsed) > kp.keyIdleTimeout && e.evictable() {
+			delete(kp.pools, k)
+			victims = append(victims, e.pool)
+		}
+	}
+	kp.mutex.Unlock()
+
+	for _, p := range victims {
+		p.Close(ctx)
+	}
+}
+
+// evictable checks if the key has neither borrowed objects nor borrows in progress
+func (e *keyedEntry[T]) evictable() bool {
+	return e.borrowers == 0 && e.pool.Stats().InUse == 0
+}
@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// LabelStats are the stats of the borrowers with a label, see WithLabel
+type LabelStats struct {
+	// InUse is the number of objects borrowed with the label
+	InUse int
+	// Borrows is the number of borrows with the label
+	Borrows int
+	// HoldTime is the total time the objects borrowed with the label were held until being returned
+	HoldTime time.Duration
+}
+
+// labelStats returns the stats of every label, or nil if no label was used
+func (p *Pool[T]) labelStats() map[string]LabelStats {
+	if len(p.labels) == 0 {
+		return nil
+	}
+
+	stats := make(map[string]LabelStats, len(p.labels))
+	for label, s := range p.labels {
+		stats[label] = *s
+	}
+	for _, e := range p.locked {
+		if e.label != "" {
+			s := stats[e.label]
+			s.InUse++
+			stats[e.label] = s
+		}
+	}
+	return stats
+}
+
+// borrowed accounts for an object borrowed with a label, recording the stack of the borrower if debugging
+func (p *Pool[T]) borrowed(e *entry) {
+	e.stack, e.goroutine = "", 0
+	if p.debugStacks {
+		e.stack, e.goroutine = string(debug.Stack()), goroutineID()
+	}
+
+	if e.label == "" {
+		return
+	}
+	if p.labels == nil {
+		p.labels = map[string]*LabelStats{}
+	}
+	s, ok := p.labels[e.label]
+	if !ok {
+		s = &LabelStats{}
+		p.labels[e.label] = s
+	}
+	s.Borrows++
+}
+
+// held accounts for the time an object borrowed with a label was held
+func (p *Pool[T]) held(e *entry, now time.Time) {
+	if s, ok := p.labels[e.label]; ok && e.label != "" {
+		s.HoldTime += now.Sub(e.lastBorrowed)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package pool
+
+import (
+	"context"
+	"iter"
+)
+
+// Leases returns an iterator borrowing n objects, one at a time, each returned to the pool at the end of its iteration,
+// or invalidated if the iteration panics. A borrow error is yielded and ends the iteration.
+func (p *Pool[T]) Leases(ctx context.Context, n int) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		for range n {
+			o, err := p.Borrow(ctx)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			more := true
+			_ = call[T](ctx, p, o, func() error {
+				more = yield(o, nil)
+				return nil
+			})
+			if !more {
+				return
+			}
+		}
+	}
+}
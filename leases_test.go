@@ -0,0 +1,54 @@
+//go:build go1.23
+
+package pool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quintans/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeases(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	// each object is returned at the end of its iteration, so a single object is enough
+	count := 0
+	for o, err := range p.Leases(ctx, 3) {
+		require.NoError(t, err)
+		assert.Equal(t, "foo", o.name)
+		assert.Equal(t, 1, p.InUse())
+		count++
+	}
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 1, p.Idle())
+
+	// breaking out returns the object
+	for range p.Leases(ctx, 3) {
+		break
+	}
+	assert.Equal(t, 0, p.InUse())
+
+	// a borrow error ends the iteration
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	defer p.Return(ctx, f)
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	for o, err := range p.Leases(tctx, 3) {
+		assert.Nil(t, o)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+}
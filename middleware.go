@@ -0,0 +1,181 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Middleware wraps the operations of a pool, to add cross-cutting concerns like logging, metrics or fault injection.
+// Each method must call next to carry on the operation, unless it wants to short circuit it.
+// Borrow and Return wrap the borrow and return of single objects, and of each object of BorrowN and ReturnN.
+type Middleware[T any] interface {
+	Create(ctx context.Context, next func(context.Context) (*T, error)) (*T, error)
+	Expire(ctx context.Context, o *T, next func(context.Context, *T))
+	Borrow(ctx context.Context, next func(context.Context) (*T, error)) (*T, error)
+	Return(ctx context.Context, o *T, next func(context.Context, *T))
+}
+
+// Middlewares adds middlewares to the pool. The first one is the outermost.
+func Middlewares[T any](middlewares ...Middleware[T]) Option[T] {
+	return func(p *Pool[T]) {
+		p.middlewares = append(p.middlewares, middlewares...)
+	}
+}
+
+// PassThrough is a middleware that only calls next.
+// It is meant to be embedded by middlewares that wrap only some operations.
+type PassThrough[T any] struct{}
+
+func (PassThrough[T]) Create(ctx context.Context, next func(context.Context) (*T, error)) (*T, error) {
+	return next(ctx)
+}
+
+func (PassThrough[T]) Expire(ctx context.Context, o *T, next func(context.Context, *T)) {
+	next(ctx, o)
+}
+
+func (PassThrough[T]) Borrow(ctx context.Context, next func(context.Context) (*T, error)) (*T, error) {
+	return next(ctx)
+}
+
+func (PassThrough[T]) Return(ctx context.Context, o *T, next func(context.Context, *T)) {
+	next(ctx, o)
+}
+
+// Logging logs every operation with its duration, and its error if it failed.
+func Logging[T any](logger *slog.Logger, level slog.Level) Middleware[T] {
+	return logging[T]{logger: logger, level: level}
+}
+
+type logging[T any] struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+func (l logging[T]) log(ctx context.Context, op string, start time.Time, err error) {
+	attrs := []slog.Attr{slog.Duration("duration", time.Since(start))}
+	if info, ok := InfoFromContext(ctx); ok {
+		attrs = append(attrs, slog.String("pool", info.Name))
+	}
+	level := l.level
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		level = slog.LevelError
+	}
+	l.logger.LogAttrs(ctx, level, op, attrs...)
+}
+
+func (l logging[T]) Create(ctx context.Context, next func(context.Context) (*T, error)) (*T, error) {
+	start := time.Now()
+	o, err := next(ctx)
+	l.log(ctx, "create", start, err)
+	return o, err
+}
+
+func (l logging[T]) Expire(ctx context.Context, o *T, next func(context.Context, *T)) {
+	start := time.Now()
+	next(ctx, o)
+	l.log(ctx, "expire", start, nil)
+}
+
+func (l logging[T]) Borrow(ctx context.Context, next func(context.Context) (*T, error)) (*T, error) {
+	start := time.Now()
+	o, err := next(ctx)
+	l.log(ctx, "borrow", start, err)
+	return o, err
+}
+
+func (l logging[T]) Return(ctx context.Context, o *T, next func(context.Context, *T)) {
+	start := time.Now()
+	next(ctx, o)
+	l.log(ctx, "return", start, nil)
+}
+
+// CreateTimeout bounds the time taken to create an object.
+func CreateTimeout[T any](timeout time.Duration) Middleware[T] {
+	return createTimeout[T]{timeout: timeout}
+}
+
+type createTimeout[T any] struct {
+	PassThrough[T]
+	timeout time.Duration
+}
+
+func (c createTimeout[T]) Create(ctx context.Context, next func(context.Context) (*T, error)) (*T, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return next(ctx)
+}
+
+// applyMiddlewares wraps create and expire with the middlewares
+func (p *Pool[T]) applyMiddlewares() {
+	p.create = p.wrapCreate(p.create)
+	if p.canary != nil {
+		p.canary.create = p.wrapCreate(p.canary.create)
+	}
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		mw, expire := p.middlewares[i], p.expire
+		p.expire = func(ctx context.Context, o *T) {
+			mw.Expire(ctx, o, expire)
+		}
+	}
+}
+
+// wrapCreate wraps a create function with the middlewares
+func (p *Pool[T]) wrapCreate(create func(context.Context) (*T, error)) func(context.Context) (*T, error) {
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		mw, next := p.middlewares[i], create
+		create = func(ctx context.Context) (*T, error) {
+			return mw.Create(ctx, next)
+		}
+	}
+	return create
+}
+
+// borrow borrows an object through the middlewares
+func (p *Pool[T]) borrow(ctx context.Context, req borrowRequest[T], cfg borrowConfig) (*T, Lease, error) {
+	if len(p.middlewares) == 0 {
+		return p.borrowObject(ctx, req, cfg)
+	}
+
+	var lease Lease
+	next := func(ctx context.Context) (*T, error) {
+		o, l, err := p.borrowObject(ctx, req, cfg)
+		lease = l
+		return o, err
+	}
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		mw, inner := p.middlewares[i], next
+		next = func(ctx context.Context) (*T, error) {
+			return mw.Borrow(ctx, inner)
+		}
+	}
+	o, err := next(p.withInfo(ctx))
+	return o, lease, err
+}
+
+// giveBack returns an object through the middlewares
+func (p *Pool[T]) giveBack(ctx context.Context, o *T) {
+	if len(p.middlewares) == 0 {
+		p.releaseLocked(ctx, o)
+		return
+	}
+
+	next := p.releaseLocked
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		mw, inner := p.middlewares[i], next
+		next = func(ctx context.Context, o *T) {
+			mw.Return(ctx, o, inner)
+		}
+	}
+	next(p.withInfo(ctx), o)
+}
+
+// releaseLocked releases the object, taking the lock
+func (p *Pool[T]) releaseLocked(ctx context.Context, o *T) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.release(ctx, o)
+}
@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errNoPermit = errors.New("no global permit available")
+
+// Permits coordinates the number of objects across processes, like replicas sharing the max connections of a backend.
+// See the poolredis package for an implementation backed by Redis.
+type Permits interface {
+	// Acquire takes a permit for a new object, returning false if there is none available
+	Acquire(ctx context.Context) (bool, error)
+	// Release gives back the permit of an expired object
+	Release(ctx context.Context) error
+}
+
+type permits struct {
+	Permits
+	retry time.Duration
+}
+
+// GlobalPermits makes the pool acquire a permit before creating an object and release it after expiring one.
+// When there is no permit available, borrowers wait for an object to be returned, trying again every retry.
+// Exported objects keep their permit, to be released by the pool importing them.
+func GlobalPermits[T any](p Permits, retry time.Duration) Option[T] {
+	return func(pool *Pool[T]) {
+		pool.permits = &permits{
+			Permits: p,
+			retry:   retry,
+		}
+	}
+}
+
+// acquire takes a global permit, if they are used
+func (p *permits) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	ok, err := p.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("on acquiring a global permit: %w", err)
+	}
+	if !ok {
+		return errNoPermit
+	}
+	return nil
+}
+
+// release gives back a global permit, if they are used
+func (p *permits) release(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	if err := p.Release(ctx); err != nil {
+		return fmt.Errorf("on releasing a global permit: %w", err)
+	}
+	return nil
+}
+
+// denied gives back the shared budget of a creation without a global permit,
+// waking up the borrowers to try again later
+func (p *Pool[T]) denied() {
+	if p.budget != nil {
+		p.budget.release()
+	}
+	time.AfterFunc(p.permits.retry, p.wake)
+}
+
+// releasePermit gives back the global permit of an expired object
+func (p *Pool[T]) releasePermit(ctx context.Context) {
+	if err := p.permits.release(ctx); err != nil {
+		p.errLogger(p.withInfo(ctx), err, "failed to release a global permit")
+	}
+}
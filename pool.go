@@ -5,11 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"sync"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
-var ErrPoolClosed = errors.New("pool is closed")
+var (
+	ErrPoolClosed    = errors.New("pool is closed")
+	ErrPoolExhausted = errors.New("pool is exhausted")
+	ErrRateLimited   = errors.New("borrow rate limit exceeded")
+	ErrInvalidConfig = errors.New("invalid pool configuration")
+	ErrUnhealthy     = errors.New("pool is unhealthy")
+)
 
 type Option[T any] func(*Pool[T])
 
@@ -31,15 +40,66 @@ func BorrowTimeout[T any](borrowTimeout time.Duration) Option[T] {
 	}
 }
 
-func Size[T any](size int) Option[T] {
+// MaxLifetime sets the maximum amount of time an object may exist since its creation.
+// Objects past their lifetime are expired instead of being reused. Zero means no limit.
+func MaxLifetime[T any](maxLifetime time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.maxLifetime = maxLifetime
+	}
+}
+
+// DetachedShutdown makes the objects expired on shutdown receive a context detached from the cancelled pool context,
+// so that a graceful close can still be done.
+// If timeout is positive, the shutdown context will have that deadline.
+func DetachedShutdown[T any](timeout time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.detachedShutdown = true
+		p.shutdownTimeout = timeout
+	}
+}
+
+// ExpireTimeout sets the deadline of the context passed to each expire call,
+// so that a stuck destroy cannot wedge the janitor or the shutdown. Zero means no deadline.
+func ExpireTimeout[T any](expireTimeout time.Duration) Option[T] {
 	return func(p *Pool[T]) {
-		if size < 1 {
-			size = 1
+		p.expireTimeout = expireTimeout
+	}
+}
+
+// Reserve reserves n objects of the pool size to borrowers of the given class, see WithClass.
+// Borrowers of other classes cannot use the reserved capacity, even if it is idle.
+func Reserve[T any](class string, n int) Option[T] {
+	return func(p *Pool[T]) {
+		if n < 1 {
+			delete(p.reservations, class)
+			return
 		}
-		p.size = size
+		p.reservations[class] = n
 	}
 }
 
+// unbounded is the size of a pool without a cap on the number of objects
+const unbounded = math.MaxInt / 2
+
+// Size sets the maximum number of objects. Zero means no limit,
+// leaving only the lifecycle management, like the idle timeout, to the pool.
+func Size[T any](size int) Option[T] {
+	return func(p *Pool[T]) {
+		p.size = capped(size)
+	}
+}
+
+// capped returns the size to use for the given one, where zero means unbounded
+func capped(size int) int {
+	switch {
+	case size == 0:
+		return unbounded
+	case size < 1:
+		return 1
+	}
+	return size
+}
+
 func MinIdle[T any](minIdle int) Option[T] {
 	return func(p *Pool[T]) {
 		if minIdle < 0 {
@@ -49,6 +109,56 @@ func MinIdle[T any](minIdle int) Option[T] {
 	}
 }
 
+// ValidationErrorPolicy is what happens to a borrow when validating an idle object fails with an error
+type ValidationErrorPolicy int
+
+const (
+	// ValidationErrorFail fails the borrow with the error
+	ValidationErrorFail ValidationErrorPolicy = iota
+	// ValidationErrorExpire logs the error, expires the object and tries the next one
+	ValidationErrorExpire
+)
+
+// OnValidationError sets the policy for validation errors on borrow. Defaults to ValidationErrorFail.
+func OnValidationError[T any](policy ValidationErrorPolicy) Option[T] {
+	return func(p *Pool[T]) {
+		p.validationErrorPolicy = policy
+	}
+}
+
+// ValidationInterval skips validating an object on borrow if it was validated,
+// created or returned within the last d, since objects rarely die that fast.
+// This also cuts the latency of a borrower waiting for an object, which gets the returned object without validating it.
+func ValidationInterval[T any](d time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.validationInterval = d
+	}
+}
+
+// MaxBorrowValidations bounds the number of idle objects that can fail validation in a single borrow.
+// Past it, a new object is created instead. The objects failing validation are expired in the background.
+func MaxBorrowValidations[T any](n int) Option[T] {
+	return func(p *Pool[T]) {
+		p.maxBorrowValidations = n
+	}
+}
+
+// MaxOverflow allows creating up to n objects beyond the size during spikes, when there are no idle objects.
+// Objects beyond the size are expired when returned, instead of being kept idle.
+func MaxOverflow[T any](n int) Option[T] {
+	return func(p *Pool[T]) {
+		p.maxOverflow = n
+	}
+}
+
+// Lazy makes New never create objects, so that it succeeds even if the objects cannot be created.
+// The min idle objects are created in the background, and failures are reported to the error logger.
+func Lazy[T any]() Option[T] {
+	return func(p *Pool[T]) {
+		p.lazy = true
+	}
+}
+
 func ErrLogger[T any](errLogger func(ctx context.Context, err error, msg string)) Option[T] {
 	return func(p *Pool[T]) {
 		p.errLogger = errLogger
@@ -61,183 +171,885 @@ func Validate[T any](validate func(context.Context, *T) (bool, error)) Option[T]
 	}
 }
 
+// entry holds the bookkeeping of a pooled object
+type entry struct {
+	createdAt time.Time
+	// since is when the object was borrowed or became idle
+	since        time.Time
+	lastBorrowed time.Time
+	borrows      int
+	generation   uint64
+	// validatedAt is when the object was last known to be valid, by validation or by being used
+	validatedAt time.Time
+	// label, class, stack and goroutine of the current borrower
+	label     string
+	class     string
+	stack     string
+	goroutine uint64
+	// reported is true if the borrow was reported as abandoned
+	reported bool
+	// jitter is the extra time before the object expires, see ExpiryJitter
+	jitter time.Duration
+	// degraded is true if the object failed validation and is kept to be served stale, see ServeStale
+	degraded bool
+	// quarantinedAt is when the object was quarantined after failing validation, see Quarantine
+	quarantinedAt time.Time
+}
+
+func (e *entry) borrow(now time.Time, cfg borrowConfig) {
+	e.since = now
+	e.lastBorrowed = now
+	e.borrows++
+	e.label = cfg.label
+	e.class = cfg.class
+	e.reported = false
+}
+
+func (e *entry) lease(reused bool) Lease {
+	return Lease{
+		Reused:   reused,
+		Age:      e.since.Sub(e.createdAt),
+		Degraded: e.degraded,
+	}
+}
+
+// Lease describes how a borrowed object was obtained
+type Lease struct {
+	// Reused is true if the object was an idle one, and false if it was freshly created
+	Reused bool
+	// Age is the time since the object was created
+	Age time.Duration
+	// Degraded is true if the object failed validation and was served because no object could be created, see ServeStale
+	Degraded bool
+}
+
 type Pool[T any] struct {
-	cond             *Cond
+	cond             WaitStrategy
 	mutex            sync.Mutex
 	errLogger        func(ctx context.Context, err error, msg string)
 	janitorSleep     time.Duration
 	idleTimeout      time.Duration
 	borrowTimeout    time.Duration
-	size             int
-	minIdle          int
-	locked, unlocked map[*T]time.Time
-	create           func(context.Context) (*T, error)
-	validate         func(context.Context, *T) (bool, error)
-	expire           func(context.Context, *T)
-	done             chan struct{}
-	closed           bool
+	maxLifetime      time.Duration
+	shutdownTimeout  time.Duration
+	expireTimeout    time.Duration
+	detachedShutdown bool
+	generation       uint64
+	minGeneration    uint64
+	rotationRate     int
+	waiters          int
+	// woken is set when every current waiter was already woken up by a Broadcast
+	woken bool
+	// number of waiters per priority, class and number of objects
+	priorities map[waiter]int
+	// reservations is the capacity reserved for each borrower class
+	reservations map[string]int
+	// affinity binds a key to the object last borrowed for it
+	affinity map[any]*T
+	// affinityKeys are the keys bound to each object, from the oldest to the newest
+	affinityKeys          map[*T][]any
+	maxAffinityKeys       int
+	size                  int
+	minIdle               int
+	locked, unlocked      map[*T]*entry
+	create                func(context.Context) (*T, error)
+	validate              func(context.Context, *T) (bool, error)
+	expire                func(context.Context, *T)
+	done                  chan struct{}
+	ticks                 chan chan struct{}
+	replenish             chan struct{}
+	lastSweep             time.Time
+	lastError             error
+	lastErrorAt           time.Time
+	events                chan Event
+	slowCreate            time.Duration
+	slowBorrowWait        time.Duration
+	warnLogger            func(ctx context.Context, msg string, attrs ...slog.Attr)
+	waitShare             float64
+	createBudget          time.Duration
+	maxOverflow           int
+	peaks                 Peaks
+	cost                  func(*T) int64
+	labels                map[string]*LabelStats
+	debugStacks           bool
+	strict                bool
+	expired               *recent[T]
+	abandonedPolicy       AbandonedPolicy
+	replaceOnExpire       bool
+	replacements          int
+	warmupConcurrency     int
+	expiryJitter          time.Duration
+	pressure              <-chan struct{}
+	creating              int
+	pending               map[string]int
+	precreate             *precreate
+	frozen                bool
+	freezeReason          string
+	softClosed            bool
+	cleanups              map[*T][]func(context.Context)
+	parents               []Dependency
+	dependents            []dependent
+	initialAttempts       int
+	initialBackoff        time.Duration
+	baseContext           context.Context
+	waitTimeout           time.Duration
+	createGrace           time.Duration
+	failFast              *failFast
+	journal               *ring[Event]
+	history               *ring[StatsSample]
+	churn                 *churn
+	degraded              map[*T]*entry
+	quarantine            map[*T]*entry
+	quarantineCap         int
+	quarantineTime        time.Duration
+	permits               *permits
+	queue                 *waitQueue
+	identity              func(*T) string
+	closed                bool
+	cancel                context.CancelFunc
+	budget                *Budget
+	limiter               *rate.Limiter
+	admission             *admission
+	autoScaling           *AutoScaling
+	schedule              []Profile
+	activeProfile         int
+	baseSize              int
+	baseMinIdle           int
+	waitersSince          time.Time
+	lowUsageSince         time.Time
+	name                  string
+	attributes            []slog.Attr
+	registry              *Registry
+	middlewares           []Middleware[T]
+	canary                *canary[T]
+	lazy                  bool
+	validationInterval    time.Duration
+	deepValidate          func(context.Context, *T) (bool, error)
+	maxBorrowValidations  int
+	background            sync.WaitGroup
+	validationErrorPolicy ValidationErrorPolicy
 }
 
-func New[T any](
-	ctx context.Context,
+// newPool creates a pool with the default configuration and applies the options
+func newPool[T any](
 	create func(context.Context) (*T, error),
 	expire func(context.Context, *T),
-	options ...Option[T],
-) (*Pool[T], error) {
+	options []Option[T],
+) *Pool[T] {
 	p := &Pool[T]{
-		cond: NewCond(),
-		errLogger: func(ctx context.Context, err error, msg string) {
-			slog.ErrorContext(ctx, msg, "error", err.Error())
-		},
+		cond:          NewCond(),
 		create:        create,
 		validate:      func(context.Context, *T) (bool, error) { return true, nil },
 		expire:        expire,
 		janitorSleep:  5 * time.Second,
 		idleTimeout:   30 * time.Second,
 		borrowTimeout: 30 * time.Second,
+		createGrace:   10 * time.Second,
 		size:          5,
 		minIdle:       0,
-		locked:        map[*T]time.Time{},
-		unlocked:      map[*T]time.Time{},
+		locked:        map[*T]*entry{},
+		unlocked:      map[*T]*entry{},
+		priorities:    map[waiter]int{},
+		reservations:  map[string]int{},
+		pending:       map[string]int{},
+		affinity:      map[any]*T{},
+		affinityKeys:  map[*T][]any{},
+		cleanups:      map[*T][]func(context.Context){},
+		registry:      DefaultRegistry,
+		done:          make(chan struct{}),
+		ticks:         make(chan chan struct{}),
+		replenish:     make(chan struct{}, 1),
+	}
+
+	p.errLogger = func(ctx context.Context, err error, msg string) {
+		attrs := append(p.Attributes(), slog.String("error", err.Error()))
+		slog.LogAttrs(ctx, slog.LevelError, msg, attrs...)
+	}
+	p.warnLogger = func(ctx context.Context, msg string, attrs ...slog.Attr) {
+		slog.LogAttrs(ctx, slog.LevelWarn, msg, append(p.Attributes(), attrs...)...)
 	}
 
 	for _, opt := range options {
 		opt(p)
 	}
 
+	return p
+}
+
+func New[T any](
+	ctx context.Context,
+	create func(context.Context) (*T, error),
+	expire func(context.Context, *T),
+	options ...Option[T],
+) (*Pool[T], error) {
+	p := newPool(create, expire, options)
+	if err := p.config().Validate(); err != nil {
+		return nil, fmt.Errorf("on new pool: %w", err)
+	}
+	if err := p.depend(); err != nil {
+		return nil, fmt.Errorf("on new pool: %w", err)
+	}
+
+	p.applyMiddlewares()
+	p.baseSize, p.baseMinIdle, p.activeProfile = p.size, p.minIdle, -1
+	p.applySchedule(ctx, time.Now())
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	if p.budget != nil {
+		p.budget.attach(p)
+	}
+	if p.registry != nil {
+		p.registry.add(p)
+	}
+
 	ticker := time.NewTicker(p.janitorSleep)
 	go func() {
+		defer close(p.done)
 		defer ticker.Stop()
+		if p.lazy {
+			if err := p.warmup(ctx); err != nil {
+				p.errLogger(p.withInfo(ctx), err, "failed to warm up the pool")
+			}
+		}
 		for {
 			select {
 			case <-ctx.Done():
+				// when closed with Close, the idle objects were already expired with its context
 				p.mutex.Lock()
-				defer p.mutex.Unlock()
-
-				for o := range p.locked {
-					p.expire(ctx, o)
-				}
-				for o := range p.unlocked {
-					p.expire(ctx, o)
+				closed := p.closed
+				p.mutex.Unlock()
+				if !closed {
+					p.shutdown(ctx)
 				}
-				p.locked = map[*T]time.Time{}
-				p.unlocked = map[*T]time.Time{}
-
-				p.closed = true
-
 				return
 			case <-ticker.C:
-				err := p.CleanUp(ctx)
+				p.sweep(ctx)
+			case swept := <-p.ticks:
+				p.sweep(ctx)
+				close(swept)
+			case <-p.replenish:
+				p.mutex.Lock()
+				var err error
+				if !p.closed {
+					err = p.replaceExpired(ctx)
+				}
+				if err == nil && !p.closed {
+					err = p.keepMinIdle(ctx)
+				}
+				if err == nil {
+					err = p.precreateIdle(ctx)
+				}
+				p.mutex.Unlock()
 				if err != nil {
-					p.errLogger(ctx, err, "failed to clean up the pool")
+					p.errLogger(p.withInfo(ctx), err, "failed to replenish the idle objects")
 				}
+			case _, ok := <-p.pressure:
+				if !ok {
+					// no more signals
+					p.pressure = nil
+					continue
+				}
+				p.relieve(ctx)
 			}
 		}
 	}()
 
-	err := p.keepMinIdle(ctx)
-	if err != nil {
+	if err := p.probe(ctx); err != nil {
+		p.Close(context.WithoutCancel(ctx))
+		return nil, fmt.Errorf("on new pool: %w", err)
+	}
+
+	if p.lazy {
+		return p, nil
+	}
+
+	if err := p.warmup(ctx); err != nil {
+		p.Close(ctx)
 		return nil, err
 	}
 
 	return p, nil
 }
 
-func (p *Pool[T]) Borrow(ctx context.Context) (*T, error) {
+// Close closes the pool, like cancelling the context used to create it.
+// Idle objects are expired with the given context and borrowed objects are expired when they are returned.
+// It waits for the janitor and the background expirations to stop, unless the context is done first, so it must not be called from the pool callbacks.
+func (p *Pool[T]) Close(ctx context.Context) {
+	// shutdown before cancelling, so that the janitor does not expire the idle objects with the cancelled context
+	p.shutdown(ctx)
+	p.cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		<-p.done
+		p.background.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+	}
+}
+
+// Done returns a channel that is closed when the pool is closed and its janitor has stopped.
+func (p *Pool[T]) Done() <-chan struct{} {
+	return p.done
+}
+
+// shutdown closes the pool, expiring all idle objects.
+// Borrowed objects are expired when they are returned.
+func (p *Pool[T]) shutdown(ctx context.Context) {
+	p.closeDependents(ctx)
+
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
 	if p.closed {
-		return nil, fmt.Errorf("on borrow: %w", ErrPoolClosed)
+		return
+	}
+	if p.registry != nil {
+		p.registry.remove(p)
+	}
+	if p.budget != nil {
+		p.budget.detach(p)
+	}
+
+	if p.detachedShutdown {
+		var cancel context.CancelFunc
+		ctx = context.WithoutCancel(ctx)
+		if p.shutdownTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, p.shutdownTimeout)
+			defer cancel()
+		}
 	}
 
 	for o := range p.unlocked {
-		ok, err := p.validate(ctx, o)
+		p.destroy(ctx, o)
+	}
+	p.unlocked = map[*T]*entry{}
+	for o := range p.quarantine {
+		delete(p.quarantine, o)
+		p.destroy(ctx, o)
+	}
+	p.retireDegraded(ctx)
+
+	p.closed = true
+	if p.events != nil {
+		close(p.events)
+	}
+	p.signal()
+}
+
+func (p *Pool[T]) Borrow(ctx context.Context, options ...BorrowOption) (*T, error) {
+	o, _, err := p.BorrowLease(ctx, options...)
+	return o, err
+}
+
+// BorrowLease borrows an object like Borrow, also describing how it was obtained.
+func (p *Pool[T]) BorrowLease(ctx context.Context, options ...BorrowOption) (*T, Lease, error) {
+	return p.borrow(ctx, borrowRequest[T]{}, newBorrowConfig(options))
+}
+
+// BorrowMatching borrows an idle object satisfying match.
+// If there is none, a new object is created, evicting a non matching idle object if the pool is full.
+// A new object is not checked against match, so the caller should prepare it when it does not match.
+func (p *Pool[T]) BorrowMatching(ctx context.Context, match func(*T) bool, options ...BorrowOption) (*T, error) {
+	o, _, err := p.borrow(ctx, borrowRequest[T]{match: match}, newBorrowConfig(options))
+	return o, err
+}
+
+// borrowRequest holds the criteria for choosing an idle object
+type borrowRequest[T any] struct {
+	// match, if not nil, is the condition that an idle object must satisfy
+	match func(*T) bool
+	// key, if not nil, is the affinity key
+	key any
+}
+
+func (p *Pool[T]) borrowObject(ctx context.Context, req borrowRequest[T], cfg borrowConfig) (*T, Lease, error) {
+	if err := p.admission.admit(ctx, 1, cfg); err != nil {
+		return nil, Lease{}, fmt.Errorf("on borrow admission: %w", err)
+	}
+
+	ws := p.startWait(ctx, cfg, 1)
+	// a borrow given up while waiting is reported after releasing the lock
+	defer ws.reportAbandoned()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	defer p.endWait(&ws)
+
+	if p.closed {
+		p.violation("borrow on a closed pool")
+	}
+
+	// if the creation of an object was already allowed by the rate limit
+	permitted := false
+	for {
+		// check on every iteration, since it may have shutdown while waiting
+		if p.closed {
+			return nil, Lease{}, fmt.Errorf("on borrow: %w", ErrPoolClosed)
+		}
+		if err := p.maintenance(); err != nil {
+			return nil, Lease{}, fmt.Errorf("on borrow: %w", err)
+		}
+
+		// borrowers with higher priority are served first
+		// and capacity reserved for other classes is not used
+		if !p.outranked(cfg.priority) && p.admissible(cfg.class, 1) {
+			canCreate := p.objectCount() < p.size
+			if !cfg.preferFresh || !canCreate {
+				o, e, err := p.borrowIdle(ctx, req, cfg)
+				if err != nil {
+					return nil, Lease{}, err
+				}
+				if o != nil {
+					p.bind(req.key, o)
+					return o, e.lease(true), nil
+				}
+			}
+			// beyond the size, overflow objects may be created when there are no idle ones.
+			// Degraded objects give way to new ones.
+			canCreate = p.objectCount()-p.degradedCount() < p.size+p.maxOverflow
+
+			// make room for a matching object
+			if !canCreate && req.match != nil {
+				canCreate = p.evictIdle(ctx)
+			}
+
+			if canCreate && !p.healthy(time.Now()) {
+				if o, e := p.borrowDegraded(cfg); o != nil {
+					return o, e.lease(true), nil
+				}
+				return nil, Lease{}, fmt.Errorf("on borrow: %w", ErrUnhealthy)
+			}
+
+			if canCreate && !permitted {
+				waited, err := p.throttle(ctx, 1)
+				if err != nil {
+					return nil, Lease{}, fmt.Errorf("on borrow while throttled: %w", err)
+				}
+				permitted = true
+				if waited {
+					continue
+				}
+			}
+
+			if canCreate {
+				p.missed(time.Now())
+				o, err := p.createForBorrow(ctx, cfg.class)
+				if err == nil {
+					e := p.newEntry()
+					e.borrow(e.createdAt, cfg)
+					p.locked[o] = e
+					p.emit(EventBorrowed, o, nil)
+					p.borrowed(e)
+					p.trackPeaks()
+					p.bind(req.key, o)
+					return o, e.lease(false), nil
+				}
+				if !errors.Is(err, errNoBudget) {
+					if o, e := p.borrowDegraded(cfg); o != nil {
+						return o, e.lease(true), nil
+					}
+					return nil, Lease{}, fmt.Errorf("on borrow: %w", err)
+				}
+				// the shared budget is exhausted, so we wait for it to be released
+			}
+		}
+
+		if cfg.noWait {
+			return nil, Lease{}, fmt.Errorf("on borrow: %w", ErrPoolExhausted)
+		}
+
+		// we reached the limit of the pool, wait for an object to be released.
+		if err := p.await(&ws, "borrow"); err != nil {
+			return nil, Lease{}, err
+		}
+	}
+}
+
+// borrowIdle borrows the first valid idle object satisfying the request, expiring the invalid ones.
+// The object bound to the affinity key, if any, is tried first.
+// It returns nil if there is no such object.
+func (p *Pool[T]) borrowIdle(ctx context.Context, req borrowRequest[T], cfg borrowConfig) (*T, *entry, error) {
+	// number of objects that failed validation
+	failed := 0
+	if req.key != nil {
+		o := p.affinity[req.key]
+		if e, ok := p.unlocked[o]; ok {
+			ok, invalid, err := p.takeIdle(ctx, o, e, cfg)
+			if err != nil || ok {
+				return o, e, err
+			}
+			if invalid {
+				failed++
+			}
+		}
+	}
+
+	for o, e := range p.unlocked {
+		if p.maxBorrowValidations > 0 && failed >= p.maxBorrowValidations {
+			// give up on the idle objects and let a new one be created
+			break
+		}
+		if req.match != nil && !req.match(o) && !p.stale(e, time.Now()) {
+			continue
+		}
+		ok, invalid, err := p.takeIdle(ctx, o, e, cfg)
 		if err != nil {
-			return nil, fmt.Errorf("on validating on borrow: %w", err)
+			return nil, nil, err
 		}
 		if ok {
-			delete(p.unlocked, o)
-			p.locked[o] = time.Now()
-			return o, nil
+			return o, e, nil
+		}
+		if invalid {
+			failed++
 		}
-
-		delete(p.unlocked, o)
-		p.expire(ctx, o)
 	}
 
-	// if we reached the limit of the pool, wait for a new one to be released
-	for !p.closed && p.objectCount() >= p.size {
-		p.mutex.Unlock()
-		err := p.cond.Wait(ctx)
-		p.mutex.Lock()
+	return nil, nil, nil
+}
+
+// takeIdle borrows the idle object if it is valid, otherwise expires it.
+// invalid reports if the object failed validation.
+func (p *Pool[T]) takeIdle(ctx context.Context, o *T, e *entry, cfg borrowConfig) (ok bool, invalid bool, err error) {
+	now := time.Now()
+	ok = !p.stale(e, now)
+	if ok && cfg.validate && now.Sub(e.validatedAt) >= p.validationInterval {
+		ok, err = p.validate(p.callbackContext(ctx), o)
 		if err != nil {
-			return nil, fmt.Errorf("on borrow while waiting: %w", err)
+			p.failed(err)
+			if p.validationErrorPolicy == ValidationErrorFail {
+				return false, false, fmt.Errorf("on validating on borrow: %w", err)
+			}
+			p.errLogger(p.withInfo(ctx), fmt.Errorf("on object %s: %w", p.identify(o), err), "failed to validate an idle object, expiring it")
+			ok, err = false, nil
 		}
-		// check again, since it may have shutdown in the meantime
-		if p.closed {
-			return nil, fmt.Errorf("on borrow: %w", ErrPoolClosed)
+		if !ok {
+			invalid = true
+			p.invalidated(o)
 		}
+		e.validatedAt = now
 	}
 
-	o, err := p.create(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("on borrow: %w", err)
+	delete(p.unlocked, o)
+	if invalid && p.degraded != nil {
+		// kept aside, to be served if no object can be created
+		e.degraded = true
+		p.degraded[o] = e
+		return false, invalid, nil
 	}
-	p.locked[o] = time.Now()
-	return o, nil
+	if invalid && p.quarantined(o, e, now) {
+		return false, invalid, nil
+	}
+	if !ok {
+		if invalid && p.maxBorrowValidations > 0 {
+			p.destroyLater(ctx, o)
+		} else {
+			p.destroy(ctx, o)
+		}
+		return false, invalid, nil
+	}
+
+	e.borrow(now, cfg)
+	p.locked[o] = e
+	p.emit(EventBorrowed, o, nil)
+	p.borrowed(e)
+	p.trackPeaks()
+	return true, false, nil
 }
 
-func (p *Pool[T]) Return(ctx context.Context, o *T) {
+// evictIdle expires one idle object, returning false if there was none
+func (p *Pool[T]) evictIdle(ctx context.Context) bool {
+	for o := range p.unlocked {
+		delete(p.unlocked, o)
+		p.destroy(ctx, o)
+		return true
+	}
+	return false
+}
+
+// BorrowAllIdle atomically borrows every idle object.
+// The caller is responsible for returning each one of them.
+func (p *Pool[T]) BorrowAllIdle(ctx context.Context) ([]*T, error) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
 	if p.closed {
+		return nil, fmt.Errorf("on borrow all idle: %w", ErrPoolClosed)
+	}
+	if err := p.maintenance(); err != nil {
+		return nil, fmt.Errorf("on borrow all idle: %w", err)
+	}
+
+	now := time.Now()
+	objs := make([]*T, 0, len(p.unlocked))
+	for o, e := range p.unlocked {
+		delete(p.unlocked, o)
+		e.borrow(now, borrowConfig{})
+		p.locked[o] = e
+		objs = append(objs, o)
+	}
+
+	return objs, nil
+}
+
+func (p *Pool[T]) Return(ctx context.Context, o *T) {
+	p.giveBack(ctx, o)
+}
+
+// release returns the object to the idle set, or expires it if stale or if the pool is closed
+func (p *Pool[T]) release(ctx context.Context, o *T) {
+	if p.closed {
+		// late return of an object borrowed before the shutdown
+		if e, ok := p.locked[o]; ok {
+			p.held(e, time.Now())
+			delete(p.locked, o)
+			p.destroy(ctx, o)
+		} else if o != nil {
+			p.misuse("return", o)
+		}
 		return
 	}
 
 	if o != nil {
+		now := time.Now()
+		e, ok := p.locked[o]
+		if ok {
+			p.held(e, now)
+		} else {
+			p.misuse("return", o)
+			e = p.newEntry()
+		}
 		delete(p.locked, o)
-		p.unlocked[o] = time.Now()
-		p.cond.Broadcast()
+		// objects beyond the size are never kept idle
+		if p.stale(e, now) || p.objectCount() >= p.size {
+			p.destroy(ctx, o)
+		} else {
+			e.since = now
+			e.validatedAt = now
+			e.label = ""
+			e.class = ""
+			if e.degraded {
+				p.degraded[o] = e
+			} else {
+				p.unlocked[o] = e
+			}
+		}
+		p.emit(EventReturned, o, nil)
+		p.trackPeaks()
+		p.signal()
+	}
+}
+
+// Invalidate removes a borrowed object from the pool and expires it, instead of returning it.
+func (p *Pool[T]) Invalidate(ctx context.Context, o *T) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if o == nil {
+		return
+	}
+
+	e, ok := p.locked[o]
+	if !ok {
+		p.misuse("invalidate", o)
+		return
+	}
+	p.held(e, time.Now())
+	delete(p.locked, o)
+	p.invalidated(o)
+	p.destroy(ctx, o)
+	p.signal()
+}
+
+// InvalidateAll marks all the existing objects as stale.
+// Idle objects are expired immediately and borrowed objects will be expired when returned.
+// Objects created afterwards are not affected.
+func (p *Pool[T]) InvalidateAll(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("on invalidate all: %w", ErrPoolClosed)
+	}
+
+	p.generation++
+	p.minGeneration = p.generation
+	p.rotationRate = 0
+	for o := range p.unlocked {
+		delete(p.unlocked, o)
+		p.destroy(ctx, o)
+	}
+	p.retireDegraded(ctx)
+	p.signal()
+
+	err := p.keepMinIdle(ctx)
+	if err != nil {
+		return fmt.Errorf("on invalidate all: %w", err)
 	}
+
+	return nil
+}
+
+// SetCreate replaces the function creating the objects, like when the credentials change.
+// The existing objects are marked as stale, so idle objects are expired when found and borrowed objects when returned.
+func (p *Pool[T]) SetCreate(create func(context.Context) (*T, error)) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.create = p.wrapCreate(create)
+	p.generation++
+	p.minGeneration = p.generation
+	p.rotationRate = 0
+}
+
+// ForEachIdle calls fn for each idle object while holding the pool lock, so no concurrent
+// Borrow can take an object while it is being visited. Iteration stops when fn returns false.
+func (p *Pool[T]) ForEachIdle(ctx context.Context, fn func(*T) bool) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("on for each idle: %w", ErrPoolClosed)
+	}
+
+	for o := range p.unlocked {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("on for each idle: %w", err)
+		}
+		if !fn(o) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// CleanUpReport describes what a clean up did
+type CleanUpReport struct {
+	// Evicted is the number of idle objects expired for being idle for too long or stale
+	Evicted int
+	// Reclaimed is the number of borrowed objects expired for exceeding the borrow timeout
+	Reclaimed int
+	// Abandoned is the number of borrowed objects exceeding the borrow timeout that were left alone, see OnAbandoned
+	Abandoned int
+	// Created is the number of objects created to keep the min idle
+	Created int
 }
 
 func (p *Pool[T]) CleanUp(ctx context.Context) error {
+	_, err := p.CleanUpReport(ctx)
+	return err
+}
+
+// CleanUpReport does a clean up, like the janitor, describing what was done.
+func (p *Pool[T]) CleanUpReport(ctx context.Context) (CleanUpReport, error) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	var report CleanUpReport
 	if p.closed {
-		return nil
+		return report, nil
 	}
 
-	expired := false
 	now := time.Now()
-	for o, t := range p.unlocked {
-		if now.Sub(t) > p.idleTimeout {
+	for o, e := range p.unlocked {
+		if now.Sub(e.since) > p.idleTimeout+e.jitter || p.stale(e, now) {
 			delete(p.unlocked, o)
-			p.expire(ctx, o)
-			expired = true
+			p.destroy(ctx, o)
+			p.emit(EventEvicted, o, nil)
+			report.Evicted++
 		}
 	}
-	for o, t := range p.locked {
-		if now.Sub(t) > p.borrowTimeout {
-			delete(p.locked, o)
-			p.expire(ctx, o)
-			expired = true
+	report.Evicted += p.evictDegraded(ctx, now)
+	for o, e := range p.locked {
+		if now.Sub(e.since) > p.borrowTimeout {
+			reclaimed, err := p.abandoned(ctx, o, e, now)
+			if reclaimed {
+				report.Reclaimed++
+			} else {
+				report.Abandoned++
+			}
+			if err != nil {
+				return report, fmt.Errorf("on cleanup: %w", err)
+			}
 		}
 	}
 
-	err := p.keepMinIdle(ctx)
+	p.applySchedule(ctx, now)
+	p.autoScale(ctx, now)
+
+	err := p.rotate(ctx)
 	if err != nil {
-		return fmt.Errorf("on cleanup: %w", err)
+		return report, fmt.Errorf("on cleanup: %w", err)
 	}
 
-	if expired {
-		p.cond.Broadcast()
+	count := p.objectCount()
+	err = p.keepMinIdle(ctx)
+	report.Created = p.objectCount() - count
+	if err != nil {
+		return report, fmt.Errorf("on cleanup: %w", err)
 	}
 
-	return nil
+	if report.Evicted > 0 || report.Reclaimed > 0 {
+		p.signal()
+	}
+
+	return report, nil
+}
+
+// TickJanitor runs a janitor sweep right away and waits for it to finish,
+// so that tests can exercise the eviction without waiting for JanitorSleep.
+func (p *Pool[T]) TickJanitor(ctx context.Context) error {
+	p.mutex.Lock()
+	closed := p.closed
+	p.mutex.Unlock()
+	if closed {
+		return fmt.Errorf("on tick janitor: %w", ErrPoolClosed)
+	}
+
+	swept := make(chan struct{})
+	select {
+	case p.ticks <- swept:
+	case <-p.done:
+		return fmt.Errorf("on tick janitor: %w", ErrPoolClosed)
+	case <-ctx.Done():
+		return fmt.Errorf("on tick janitor: %w", ctx.Err())
+	}
+
+	select {
+	case <-swept:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("on tick janitor: %w", ctx.Err())
+	}
+}
+
+// sweep is a janitor run
+func (p *Pool[T]) sweep(ctx context.Context) {
+	err := p.CleanUp(ctx)
+	if err != nil {
+		p.errLogger(p.withInfo(ctx), err, "failed to clean up the pool")
+	}
+	deepErr := p.deepValidateIdle(ctx)
+	if deepErr != nil {
+		p.errLogger(p.withInfo(ctx), deepErr, "failed to validate the idle objects")
+	}
+	p.rescue(ctx)
+
+	now := time.Now()
+	p.checkChurn(ctx, now)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if err == nil && deepErr == nil {
+		p.lastSweep = now
+	} else if err != nil {
+		p.failed(err)
+	}
+	p.sample(now)
 }
 
 func (p *Pool[T]) keepMinIdle(ctx context.Context) error {
@@ -246,17 +1058,219 @@ func (p *Pool[T]) keepMinIdle(ctx context.Context) error {
 		return nil
 	}
 
-	for i := idle; i < p.minIdle; i++ {
-		o, err := p.create(ctx)
+	for i := idle; i < p.minIdle && p.allowCreate(); i++ {
+		o, err := p.newObject(ctx)
+		if errors.Is(err, errNoBudget) {
+			return nil
+		}
 		if err != nil {
 			return fmt.Errorf("on keeping the idle minimum: %w", err)
 		}
-		p.unlocked[o] = time.Now()
-
+		p.unlocked[o] = p.newEntry()
+		p.trackPeaks()
 	}
 	return nil
 }
 
+// newObject creates an object, if the shared budget allows it
+func (p *Pool[T]) newObject(ctx context.Context) (*T, error) {
+	c, err := p.startCreation()
+	if err != nil {
+		return nil, err
+	}
+	o, err := c.run(p.callbackContext(ctx))
+	return p.endCreation(ctx, c, o, err)
+}
+
+// creation is an object creation in progress, so that the create call can be made without holding the lock
+type creation[T any] struct {
+	create   func(context.Context) (*T, error)
+	isCanary bool
+	start    time.Time
+	cleanups *cleanups
+	permits  *permits
+}
+
+// run acquires the global permit and calls the create function, collecting the cleanups it registers
+func (c creation[T]) run(ctx context.Context) (*T, error) {
+	if err := c.permits.acquire(ctx); err != nil {
+		return nil, err
+	}
+	o, err := c.create(context.WithValue(ctx, cleanupsKey{}, c.cleanups))
+	if err != nil {
+		// the failure to create is what matters
+		_ = c.permits.release(ctx)
+	}
+	return o, err
+}
+
+// startCreation acquires the shared budget for a new object and picks its create function
+func (p *Pool[T]) startCreation() (creation[T], error) {
+	// a soft closed pool is like one without budget, where borrowers wait for returns
+	if p.softClosed || (p.budget != nil && !p.budget.acquire()) {
+		return creation[T]{}, errNoBudget
+	}
+	create, isCanary := p.variant()
+	return creation[T]{create: create, isCanary: isCanary, start: time.Now(), cleanups: &cleanups{}, permits: p.permits}, nil
+}
+
+// endCreation accounts for the outcome of a creation
+func (p *Pool[T]) endCreation(ctx context.Context, c creation[T], o *T, err error) (*T, error) {
+	if errors.Is(err, errNoPermit) {
+		// like an exhausted shared budget, it is not a failure
+		p.denied()
+		return nil, errNoBudget
+	}
+	p.warnSlow(ctx, "slow object creation", c.start, p.slowCreate)
+	p.createdVariant(o, err, c.isCanary)
+	p.created(err)
+	if err != nil {
+		runCleanups(ctx, c.cleanups.registered())
+		p.failed(err)
+		p.emit(EventCreateFailed, nil, err)
+		if p.budget != nil {
+			p.budget.release()
+		}
+		return nil, err
+	}
+	if funcs := c.cleanups.registered(); len(funcs) > 0 {
+		p.cleanups[o] = funcs
+	}
+	p.churned()
+	p.emit(EventCreated, o, nil)
+	p.retireDegraded(ctx)
+	return o, nil
+}
+
+// destroy calls expire, bounded by the expire timeout
+func (p *Pool[T]) destroy(ctx context.Context, o *T) {
+	p.remember(o)
+	p.unbind(o)
+	p.forget(o)
+	p.topUp()
+	p.drained()
+	p.churned()
+	p.expireObject(ctx, o, p.takeCleanups(o))
+}
+
+// destroyLater expires the object in the background, so that the caller does not wait for it
+func (p *Pool[T]) destroyLater(ctx context.Context, o *T) {
+	p.remember(o)
+	p.unbind(o)
+	p.forget(o)
+	p.topUp()
+	p.drained()
+	p.churned()
+	cleanups := p.takeCleanups(o)
+	ctx = context.WithoutCancel(ctx)
+	p.background.Add(1)
+	go func() {
+		defer p.background.Done()
+		p.expireObject(ctx, o, cleanups)
+	}()
+}
+
+// topUp asks the janitor to create the missing idle objects, when there are less than min idle,
+// or a replacement for a destroyed object, see ReplaceOnExpire
+func (p *Pool[T]) topUp() {
+	if p.closed {
+		return
+	}
+	if p.replaceOnExpire {
+		p.replacements++
+	} else if len(p.unlocked) >= p.minIdle {
+		return
+	}
+	select {
+	case p.replenish <- struct{}{}:
+	default:
+		// already requested
+	}
+}
+
+// expireObject calls expire and the cleanups of the object, releasing its budget slot
+func (p *Pool[T]) expireObject(ctx context.Context, o *T, cleanups []func(context.Context)) {
+	if p.budget != nil {
+		defer p.budget.release()
+	}
+	p.expireUnbudgeted(ctx, o, cleanups)
+}
+
+// expireUnbudgeted calls expire and the cleanups of an object that does not hold a budget slot, releasing its global permit
+func (p *Pool[T]) expireUnbudgeted(ctx context.Context, o *T, cleanups []func(context.Context)) {
+	if p.expireTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.expireTimeout)
+		defer cancel()
+	}
+	ctx = p.callbackContext(ctx)
+	defer p.releasePermit(ctx)
+	defer runCleanups(ctx, cleanups)
+	p.expire(ctx, o)
+}
+
+// waiting registers (delta=1) or unregisters (delta=-1) a waiter
+func (p *Pool[T]) waiting(w waiter, delta int) {
+	if p.waiters == 0 {
+		p.waitersSince = time.Now()
+	}
+	p.waiters += delta
+	if delta > 0 {
+		// the new waiter holds a sequence taken after the last Broadcast
+		p.woken = false
+	}
+	p.trackPeaks()
+	p.priorities[w] += delta
+	if p.priorities[w] == 0 {
+		delete(p.priorities, w)
+	}
+}
+
+// waiter is the kind of a borrower waiting for objects
+type waiter struct {
+	priority int
+	class    string
+	// n is the number of objects it waits for
+	n int
+}
+
+// outranked checks if there is a waiter with a higher priority that could be served.
+// Waiters that would use the capacity reserved for other classes cannot, so they do not hold back the others.
+func (p *Pool[T]) outranked(priority int) bool {
+	for w := range p.priorities {
+		if w.priority > priority && p.admissible(w.class, w.n) {
+			return true
+		}
+	}
+	return false
+}
+
+// signal wakes up the borrowers waiting for an object, if any.
+// Once the waiters are woken up, further signals are dropped until one of them waits again,
+// since they will all check the pool anyway, so that a burst of releases only broadcasts once.
+func (p *Pool[T]) signal() {
+	if p.waiters > 0 && !p.woken {
+		p.woken = true
+		p.cond.Broadcast()
+	}
+}
+
+func (p *Pool[T]) newEntry() *entry {
+	now := time.Now()
+	return &entry{
+		createdAt:   now,
+		since:       now,
+		validatedAt: now,
+		generation:  p.generation,
+		jitter:      p.jitter(),
+	}
+}
+
+// stale checks if the object is past its lifetime or belongs to an invalidated generation
+func (p *Pool[T]) stale(e *entry, now time.Time) bool {
+	return e.generation < p.minGeneration || (p.maxLifetime > 0 && now.Sub(e.createdAt) > p.maxLifetime+e.jitter)
+}
+
 func (p *Pool[T]) objectCount() int {
-	return len(p.unlocked) + len(p.locked)
+	return len(p.unlocked) + len(p.locked) + len(p.quarantine) + len(p.degraded) + p.creating
 }
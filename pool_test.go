@@ -2,6 +2,12 @@ package pool_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -10,12 +16,23 @@ import (
 	"github.com/quintans/pool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
 )
 
 type Foo struct {
 	name string
 }
 
+// gauges keeps only the instantaneous counts of the stats
+func gauges(s pool.Stats) pool.Stats {
+	return pool.Stats{
+		Size:    s.Size,
+		Idle:    s.Idle,
+		InUse:   s.InUse,
+		Waiters: s.Waiters,
+	}
+}
+
 func TestBorrowValidate(t *testing.T) {
 	ctx := context.Background()
 
@@ -60,7 +77,7 @@ func TestIdleTimeout(t *testing.T) {
 			wg.Done()
 		},
 		pool.IdleTimeout[Foo](500*time.Millisecond),
-		pool.JanitorSleep[Foo](500*time.Millisecond),
+		pool.JanitorSleep[Foo](250*time.Millisecond),
 	)
 	require.NoError(t, err)
 
@@ -164,7 +181,7 @@ func TestMinIdle(t *testing.T) {
 }
 
 func TestCancelContext(t *testing.T) {
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
 
 	var count atomic.Int32
 	p, err := pool.New[Foo](
@@ -177,7 +194,3386 @@ func TestCancelContext(t *testing.T) {
 	)
 	require.NoError(t, err)
 
+	cancel()
 	time.Sleep(100 * time.Millisecond)
-	_, err = p.Borrow(ctx)
+	_, err = p.Borrow(context.Background())
 	require.ErrorIs(t, err, pool.ErrPoolClosed)
 }
+
+func TestForEachIdle(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](3),
+	)
+	require.NoError(t, err)
+
+	count := 0
+	err = p.ForEachIdle(ctx, func(f *Foo) bool {
+		f.name = "bar"
+		count++
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "bar", f.name)
+
+	count = 0
+	err = p.ForEachIdle(ctx, func(f *Foo) bool {
+		count++
+		return false
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestBorrowAllIdle(t *testing.T) {
+	ctx := context.Background()
+
+	var count atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			count.Add(1)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](3),
+	)
+	require.NoError(t, err)
+
+	objs, err := p.BorrowAllIdle(ctx)
+	require.NoError(t, err)
+	assert.Len(t, objs, 3)
+
+	// no idle objects left, so a new one must be created
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), count.Load())
+
+	for _, o := range objs {
+		p.Return(ctx, o)
+	}
+
+	objs, err = p.BorrowAllIdle(ctx)
+	require.NoError(t, err)
+	assert.Len(t, objs, 3)
+}
+
+func TestMaxLifetime(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {
+			expired.Add(1)
+		},
+		pool.MaxLifetime[Foo](100*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	f1, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f1)
+
+	f2, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Same(t, f1, f2)
+
+	time.Sleep(150 * time.Millisecond)
+	p.Return(ctx, f2)
+	assert.Equal(t, int32(1), expired.Load())
+
+	f3, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, f1, f3)
+}
+
+func TestInvalidate(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {
+			expired.Add(1)
+		},
+		pool.Size[Foo](1),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, pool.Stats{Size: 1, InUse: 1}, gauges(p.Stats()))
+
+	p.Invalidate(ctx, f)
+	assert.Equal(t, int32(1), expired.Load())
+	assert.Equal(t, pool.Stats{Size: 1}, gauges(p.Stats()))
+
+	f2, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, f, f2)
+}
+
+func TestDetachedShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errs := make(chan error, 1)
+	_, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			errs <- ctx.Err()
+		},
+		pool.MinIdle[Foo](1),
+		pool.DetachedShutdown[Foo](time.Second),
+	)
+	require.NoError(t, err)
+
+	cancel()
+	require.NoError(t, <-errs)
+}
+
+func TestReturnAfterClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {
+			expired.Add(1)
+		},
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+	// borrowed objects are not touched while in use
+	assert.Equal(t, int32(0), expired.Load())
+
+	p.Return(context.Background(), f)
+	assert.Equal(t, int32(1), expired.Load())
+
+	// returning it again has no effect
+	p.Return(context.Background(), f)
+	assert.Equal(t, int32(1), expired.Load())
+}
+
+func TestExpireTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	var hasDeadline atomic.Bool
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {
+			deadline, ok := ctx.Deadline()
+			hasDeadline.Store(ok && time.Until(deadline) <= time.Second)
+		},
+		pool.ExpireTimeout[Foo](time.Second),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	p.Invalidate(ctx, f)
+	assert.True(t, hasDeadline.Load())
+}
+
+func TestInvalidateAll(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {
+			expired.Add(1)
+		},
+		pool.MinIdle[Foo](2),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	err = p.InvalidateAll(ctx)
+	require.NoError(t, err)
+	// the remaining idle object was expired and the minimum idle replenished
+	assert.Equal(t, int32(1), expired.Load())
+	assert.Equal(t, pool.Stats{Size: 5, Idle: 2, InUse: 1}, gauges(p.Stats()))
+
+	p.Return(ctx, f)
+	assert.Equal(t, int32(2), expired.Load())
+	assert.Equal(t, pool.Stats{Size: 5, Idle: 2}, gauges(p.Stats()))
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {
+			expired.Add(1)
+		},
+		pool.MinIdle[Foo](3),
+		pool.IdleTimeout[Foo](2*time.Hour),
+		pool.JanitorSleep[Foo](time.Hour),
+	)
+	require.NoError(t, err)
+
+	err = p.Rotate(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), expired.Load())
+
+	require.NoError(t, p.CleanUp(ctx))
+	assert.Equal(t, int32(2), expired.Load())
+	assert.Equal(t, pool.Stats{Size: 5, Idle: 3}, gauges(p.Stats()))
+
+	require.NoError(t, p.CleanUp(ctx))
+	assert.Equal(t, int32(3), expired.Load())
+
+	// all objects were rotated
+	require.NoError(t, p.CleanUp(ctx))
+	assert.Equal(t, int32(3), expired.Load())
+	assert.Equal(t, pool.Stats{Size: 5, Idle: 3}, gauges(p.Stats()))
+}
+
+func TestInspect(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](1),
+	)
+	require.NoError(t, err)
+
+	infos := p.Inspect()
+	require.Len(t, infos, 1)
+	assert.Equal(t, pool.StateIdle, infos[0].State)
+	assert.Equal(t, 0, infos[0].Borrows)
+	assert.True(t, infos[0].LastBorrowedAt.IsZero())
+	assert.False(t, infos[0].IdleSince.IsZero())
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	infos = p.Inspect()
+	require.Len(t, infos, 1)
+	assert.Same(t, f, infos[0].Object)
+	assert.Equal(t, pool.StateBorrowed, infos[0].State)
+	assert.Equal(t, 1, infos[0].Borrows)
+	assert.False(t, infos[0].LastBorrowedAt.IsZero())
+	assert.True(t, infos[0].IdleSince.IsZero())
+}
+
+func TestWaiters(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	ctx2, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		_, _ = p.Borrow(ctx2)
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, pool.Stats{Size: 1, InUse: 1, Waiters: 1}, gauges(p.Stats()))
+
+	cancel()
+	<-done
+	assert.Equal(t, pool.Stats{Size: 1, InUse: 1}, gauges(p.Stats()))
+
+	p.Return(ctx, f)
+}
+
+func TestBorrowLease(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+	)
+	require.NoError(t, err)
+
+	f, lease, err := p.BorrowLease(ctx)
+	require.NoError(t, err)
+	assert.False(t, lease.Reused)
+	assert.Zero(t, lease.Age)
+
+	time.Sleep(50 * time.Millisecond)
+	p.Return(ctx, f)
+
+	_, lease, err = p.BorrowLease(ctx)
+	require.NoError(t, err)
+	assert.True(t, lease.Reused)
+	assert.GreaterOrEqual(t, lease.Age, 50*time.Millisecond)
+}
+
+func TestBorrowOptions(t *testing.T) {
+	ctx := context.Background()
+
+	var validations atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+		pool.Validate(func(ctx context.Context, f *Foo) (bool, error) {
+			validations.Add(1)
+			return true, nil
+		}),
+	)
+	require.NoError(t, err)
+
+	f1, err := p.Borrow(ctx, pool.WithLabel("report-job"))
+	require.NoError(t, err)
+	infos := p.Inspect()
+	require.Len(t, infos, 1)
+	assert.Equal(t, "report-job", infos[0].Label)
+	p.Return(ctx, f1)
+
+	// prefer fresh creates a new object even if there is an idle one
+	f2, err := p.Borrow(ctx, pool.WithPreferFresh())
+	require.NoError(t, err)
+	assert.NotSame(t, f1, f2)
+
+	// no validation
+	f1, err = p.Borrow(ctx, pool.WithValidate(false))
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), validations.Load())
+
+	// exhausted
+	_, err = p.Borrow(ctx, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	// higher priority waiters are served first
+	served := make(chan string, 2)
+	go func() {
+		_, err := p.Borrow(ctx, pool.WithPriority(0))
+		if err == nil {
+			served <- "low"
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	go func() {
+		_, err := p.Borrow(ctx, pool.WithPriority(10))
+		if err == nil {
+			served <- "high"
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	p.Return(ctx, f1)
+	assert.Equal(t, "high", <-served)
+	p.Return(ctx, f2)
+	assert.Equal(t, "low", <-served)
+}
+
+func TestBorrowMatching(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {
+			expired.Add(1)
+		},
+		pool.Size[Foo](2),
+	)
+	require.NoError(t, err)
+
+	isBar := func(f *Foo) bool { return f.name == "bar" }
+
+	f1, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	f1.name = "bar"
+	f2, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f2)
+	p.Return(ctx, f1)
+
+	f, err := p.BorrowMatching(ctx, isBar)
+	require.NoError(t, err)
+	assert.Same(t, f1, f)
+
+	// the only idle object does not match, so it is evicted to make room for a new one
+	f3, err := p.BorrowMatching(ctx, isBar)
+	require.NoError(t, err)
+	assert.NotSame(t, f2, f3)
+	assert.False(t, isBar(f3))
+	assert.Equal(t, int32(1), expired.Load())
+}
+
+func TestBorrowFor(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](3),
+	)
+	require.NoError(t, err)
+
+	a, err := p.BorrowFor(ctx, "a")
+	require.NoError(t, err)
+	b, err := p.BorrowFor(ctx, "b")
+	require.NoError(t, err)
+	p.Return(ctx, a)
+	p.Return(ctx, b)
+
+	for i := 0; i < 10; i++ {
+		o, err := p.BorrowFor(ctx, "b")
+		require.NoError(t, err)
+		assert.Same(t, b, o)
+		p.Return(ctx, o)
+	}
+
+	// falls back to another object when the bound one is in use
+	o, err := p.BorrowFor(ctx, "a")
+	require.NoError(t, err)
+	assert.Same(t, a, o)
+	o2, err := p.BorrowFor(ctx, "a")
+	require.NoError(t, err)
+	assert.NotSame(t, a, o2)
+
+	// the key is now bound to the last object
+	p.Return(ctx, o)
+	p.Return(ctx, o2)
+	o, err = p.BorrowFor(ctx, "a")
+	require.NoError(t, err)
+	assert.Same(t, o2, o)
+
+	// keys that cannot be map keys are rejected
+	_, err = p.BorrowFor(ctx, []string{"a"})
+	require.Error(t, err)
+}
+
+func TestMaxAffinityKeys(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+		pool.MaxAffinityKeys[Foo](1),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	// binding a key to an object forgets its previous key,
+	// so a forgotten key is served by any idle object instead of the one it was bound to
+	moved := 0
+	for range 200 {
+		a, err := p.BorrowFor(ctx, "a")
+		require.NoError(t, err)
+		b, err := p.BorrowFor(ctx, "b")
+		require.NoError(t, err)
+		p.Return(ctx, a)
+		p.Return(ctx, b)
+
+		c, err := p.BorrowFor(ctx, "c")
+		require.NoError(t, err)
+		p.Return(ctx, c)
+		key, bound := "a", a
+		if c == b {
+			key, bound = "b", b
+		}
+
+		o, err := p.BorrowFor(ctx, key)
+		require.NoError(t, err)
+		p.Return(ctx, o)
+		if o != bound {
+			moved++
+		}
+	}
+	assert.Positive(t, moved)
+}
+
+func TestBorrowN(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](4),
+		pool.MinIdle[Foo](1),
+	)
+	require.NoError(t, err)
+
+	_, err = p.BorrowN(ctx, 5)
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	objs, err := p.BorrowN(ctx, 3)
+	require.NoError(t, err)
+	assert.Len(t, objs, 3)
+	assert.Equal(t, pool.Stats{Size: 4, InUse: 3}, gauges(p.Stats()))
+
+	// there is only room for one more
+	_, err = p.BorrowN(ctx, 2, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	// concurrent batch borrowers do not deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			objs, err := p.BorrowN(ctx, 2)
+			if assert.NoError(t, err) {
+				time.Sleep(10 * time.Millisecond)
+				p.ReturnN(ctx, objs)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	p.ReturnN(ctx, objs)
+	wg.Wait()
+	assert.Equal(t, pool.Stats{Size: 4, Idle: 4}, gauges(p.Stats()))
+}
+
+func TestBorrowNLikeBorrow(t *testing.T) {
+	ctx := context.Background()
+
+	var failing atomic.Bool
+	mw := &countingMiddleware{}
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			if failing.Load() {
+				return nil, errors.New("backend down")
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+		pool.WaitTimeout[Foo](10*time.Millisecond),
+		pool.FailFast[Foo](1, time.Minute),
+		pool.Middlewares[Foo](mw),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	_, err = p.BorrowN(ctx, 0)
+	require.Error(t, err)
+	_, err = p.BorrowN(ctx, -1)
+	require.Error(t, err)
+
+	objs, err := p.BorrowN(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"create", "create", "borrow", "borrow"}, mw.ops)
+
+	// the wait timeout bounds the wait of a batch
+	_, err = p.BorrowN(ctx, 1)
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	p.ReturnN(ctx, objs)
+	assert.Equal(t, []string{"create", "create", "borrow", "borrow", "return", "return"}, mw.ops)
+
+	// a failing creation makes the pool unhealthy for batches too
+	p.InvalidateAll(ctx)
+	failing.Store(true)
+	_, err = p.BorrowN(ctx, 1)
+	require.Error(t, err)
+	_, err = p.BorrowN(ctx, 1)
+	require.ErrorIs(t, err, pool.ErrUnhealthy)
+}
+
+func TestBorrowNReservesRoom(t *testing.T) {
+	ctx := context.Background()
+
+	var created atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			created.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.BorrowN(ctx, 2)
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the room of the batch is not taken while its objects are being created
+	tctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	_, err = p.Borrow(tctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, <-done)
+	assert.Equal(t, 2, p.Stats().InUse)
+	assert.EqualValues(t, 2, created.Load())
+}
+
+func TestReserve(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](3),
+		pool.Reserve[Foo]("admin", 1),
+	)
+	require.NoError(t, err)
+
+	f1, err := p.Borrow(ctx, pool.WithClass("batch"))
+	require.NoError(t, err)
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+
+	// the last slot is reserved
+	_, err = p.Borrow(ctx, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	a, err := p.Borrow(ctx, pool.WithClass("admin"), pool.WithNoWait())
+	require.NoError(t, err)
+
+	// an idle reserved slot is still not available to other classes
+	p.Return(ctx, a)
+	_, err = p.Borrow(ctx, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	// admins can also use the shared capacity
+	p.Return(ctx, f1)
+	_, err = p.Borrow(ctx, pool.WithClass("admin"), pool.WithNoWait())
+	require.NoError(t, err)
+	_, err = p.Borrow(ctx, pool.WithClass("admin"), pool.WithNoWait())
+	require.NoError(t, err)
+}
+
+func TestReserveWhileCreating(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			time.Sleep(50 * time.Millisecond)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+		pool.Reserve[Foo]("admin", 1),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	// the slot being created for a borrower is taken, so the other one stays reserved
+	go func() { _, _ = p.Borrow(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+	_, err = p.Borrow(ctx, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	tctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	_, err = p.Borrow(tctx, pool.WithClass("admin"))
+	require.NoError(t, err)
+}
+func TestReserveNotHeldBackByInadmissibleWaiter(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+		pool.Reserve[Foo]("admin", 1),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	objs, err := p.BorrowN(ctx, 1)
+	require.NoError(t, err)
+
+	// a higher priority batch borrower waits, since the free slot is reserved
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go p.BorrowN(waitCtx, 1, pool.WithPriority(10))
+	require.Eventually(t, func() bool { return p.Stats().Waiters == 1 }, time.Second, time.Millisecond)
+
+	// it can never be served from the reserved slot, so it does not hold back the admin
+	adminCtx, adminCancel := context.WithTimeout(ctx, time.Second)
+	defer adminCancel()
+	a, err := p.Borrow(adminCtx, pool.WithClass("admin"))
+	require.NoError(t, err)
+
+	p.Return(ctx, a)
+	p.ReturnN(ctx, objs)
+}
+
+func TestView(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](3),
+	)
+	require.NoError(t, err)
+
+	v := p.View(1)
+
+	f, err := v.Borrow(ctx)
+	require.NoError(t, err)
+
+	_, err = v.Borrow(ctx, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	// the pool itself still has room
+	_, err = p.Borrow(ctx, pool.WithNoWait())
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		_, err := v.Borrow(ctx)
+		assert.NoError(t, err)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, v.Stats().Waiters)
+
+	v.Return(ctx, f)
+	<-done
+	assert.Equal(t, pool.Stats{Size: 1, InUse: 1}, v.Stats())
+}
+
+func TestSharedBudget(t *testing.T) {
+	ctx := context.Background()
+
+	budget := pool.NewBudget(2)
+	newPool := func() *pool.Pool[Foo] {
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+			func(ctx context.Context, f *Foo) {},
+			pool.SharedBudget[Foo](budget),
+		)
+		require.NoError(t, err)
+		return p
+	}
+	a := newPool()
+	b := newPool()
+
+	_, err := a.Borrow(ctx)
+	require.NoError(t, err)
+	b1, err := b.Borrow(ctx)
+	require.NoError(t, err)
+
+	_, err = a.Borrow(ctx, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	// the idle object of b is expired to make room for a
+	b.Return(ctx, b1)
+	tctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	_, err = a.Borrow(tctx)
+	require.NoError(t, err)
+	assert.Equal(t, pool.Stats{Size: 5}, gauges(b.Stats()))
+
+	// closing the budget closes all the pools
+	budget.Close(ctx)
+	_, err = a.Borrow(ctx)
+	require.ErrorIs(t, err, pool.ErrPoolClosed)
+	_, err = b.Borrow(ctx)
+	require.ErrorIs(t, err, pool.ErrPoolClosed)
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+
+	registry := pool.NewRegistry()
+	newPool := func(name string) *pool.Pool[Foo] {
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+			func(ctx context.Context, f *Foo) {},
+			pool.Name[Foo](name),
+			pool.Register[Foo](registry),
+		)
+		require.NoError(t, err)
+		return p
+	}
+	a := newPool("a")
+	b := newPool("b")
+
+	_, err := b.Borrow(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []pool.NamedStats{
+		{Name: "a", Stats: pool.Stats{Size: 5}},
+		{Name: "b", Stats: pool.Stats{Size: 5, InUse: 1, Peaks: pool.Peaks{InUse: 1}}},
+	}, registry.Stats())
+
+	a.Close(ctx)
+	require.Len(t, registry.Pools(), 1)
+	assert.Equal(t, "b", registry.Pools()[0].Name())
+
+	registry.CloseAll(ctx)
+	assert.Empty(t, registry.Pools())
+	_, err = b.Borrow(ctx)
+	require.ErrorIs(t, err, pool.ErrPoolClosed)
+}
+
+func TestAttributes(t *testing.T) {
+	ctx := context.Background()
+
+	infos := make(chan pool.Info, 1)
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			info, _ := pool.InfoFromContext(ctx)
+			infos <- info
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Name[Foo]("db"),
+		pool.Attributes[Foo](slog.String("backend", "primary")),
+	)
+	require.NoError(t, err)
+
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+
+	info := <-infos
+	assert.Equal(t, "db", info.Name)
+	assert.Equal(t, []slog.Attr{slog.String("backend", "primary")}, info.Attributes)
+	assert.Equal(t, []slog.Attr{slog.String("pool", "db"), slog.String("backend", "primary")}, p.Attributes())
+}
+
+func TestAutoScale(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+		pool.IdleTimeout[Foo](2*time.Hour),
+		pool.JanitorSleep[Foo](time.Hour),
+		pool.AutoScale[Foo](pool.AutoScaling{
+			Min:         1,
+			Max:         3,
+			ShrinkBelow: 0.5,
+		}),
+	)
+	require.NoError(t, err)
+
+	f1, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	borrowed := make(chan *Foo)
+	go func() {
+		f, err := p.Borrow(ctx)
+		assert.NoError(t, err)
+		borrowed <- f
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// grows because of the waiter
+	require.NoError(t, p.CleanUp(ctx))
+	f2 := <-borrowed
+	assert.Equal(t, pool.Stats{Size: 2, InUse: 2}, gauges(p.Stats()))
+
+	// shrinks because of low utilization
+	p.Return(ctx, f1)
+	p.Return(ctx, f2)
+	require.NoError(t, p.CleanUp(ctx))
+	assert.Equal(t, pool.Stats{Size: 1, Idle: 1}, gauges(p.Stats()))
+}
+
+func TestSchedule(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now()
+	y, m, d := now.Date()
+	tod := now.Sub(time.Date(y, m, d, 0, 0, 0, 0, now.Location()))
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+		pool.Schedule[Foo](
+			// not active
+			pool.Profile{From: tod + time.Hour, To: tod + 2*time.Hour, Size: 20},
+			// active
+			pool.Profile{Weekdays: []time.Weekday{now.Weekday()}, From: tod - time.Hour, To: tod + time.Hour, Size: 10, MinIdle: 3},
+		),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, pool.Stats{Size: 10, Idle: 3}, gauges(p.Stats()))
+
+	// a profile without size is unbounded
+	p, err = pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+		pool.Schedule[Foo](pool.Profile{From: tod - time.Hour, To: tod + time.Hour}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, pool.Stats{}, gauges(p.Stats()))
+	for range 3 {
+		_, err := p.Borrow(ctx)
+		require.NoError(t, err)
+	}
+}
+
+func TestCreateRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.CreateRateLimit[Foo](rate.Every(100*time.Millisecond), 1),
+	)
+	require.NoError(t, err)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := p.Borrow(ctx)
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = p.Borrow(tctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestBorrowRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.BorrowRateLimit[Foo](rate.Every(time.Hour), 2, pool.AdmissionReject),
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err := p.Borrow(ctx, pool.WithLabel("runaway"))
+		require.NoError(t, err)
+	}
+	_, err = p.Borrow(ctx, pool.WithLabel("runaway"))
+	require.ErrorIs(t, err, pool.ErrRateLimited)
+
+	// other borrowers are not affected
+	_, err = p.Borrow(ctx, pool.WithLabel("other"))
+	require.NoError(t, err)
+}
+
+func TestKeyedPool(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	kp := pool.NewKeyed[string, Foo](
+		ctx,
+		func(ctx context.Context, key string) (*Foo, error) { return &Foo{key}, nil },
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.MaxPerKey[string, Foo](2),
+		pool.MaxTotal[string, Foo](3),
+		pool.MaxKeys[string, Foo](2),
+	)
+	defer kp.Close(ctx)
+
+	a1, err := kp.Borrow(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", a1.name)
+	a2, err := kp.Borrow(ctx, "a")
+	require.NoError(t, err)
+
+	// per key limit
+	_, err = kp.Borrow(ctx, "a", pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	b1, err := kp.Borrow(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, "b", b1.name)
+
+	// global limit
+	_, err = kp.Borrow(ctx, "b", pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	// all keys are in use, so none can be evicted
+	_, err = kp.Borrow(ctx, "c", pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	// the least recently used idle key is evicted
+	kp.Return(ctx, "a", a1)
+	kp.Return(ctx, "a", a2)
+	kp.Return(ctx, "b", b1)
+	_, err = kp.Borrow(ctx, "b")
+	require.NoError(t, err)
+	c1, err := kp.Borrow(ctx, "c")
+	require.NoError(t, err)
+	assert.Equal(t, "c", c1.name)
+	assert.Equal(t, 2, kp.Keys())
+	assert.EqualValues(t, 2, expired.Load())
+}
+
+func TestKeyedPoolEviction(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	kp := pool.NewKeyed[string, Foo](
+		ctx,
+		func(ctx context.Context, key string) (*Foo, error) { return &Foo{key}, nil },
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.KeyIdleTimeout[string, Foo](time.Millisecond),
+	)
+	defer kp.Close(ctx)
+
+	// an object of a key without pool is expired
+	kp.Return(ctx, "gone", &Foo{"gone"})
+	assert.EqualValues(t, 1, expired.Load())
+
+	// borrows never see the pool of a key being evicted
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				f, err := kp.Borrow(ctx, "a")
+				if !assert.NoError(t, err) {
+					return
+				}
+				kp.Return(ctx, "a", f)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type countingMiddleware struct {
+	pool.PassThrough[Foo]
+	ops []string
+}
+
+func (m *countingMiddleware) Create(ctx context.Context, next func(context.Context) (*Foo, error)) (*Foo, error) {
+	m.ops = append(m.ops, "create")
+	return next(ctx)
+}
+
+func (m *countingMiddleware) Borrow(ctx context.Context, next func(context.Context) (*Foo, error)) (*Foo, error) {
+	m.ops = append(m.ops, "borrow")
+	return next(ctx)
+}
+
+func (m *countingMiddleware) Return(ctx context.Context, o *Foo, next func(context.Context, *Foo)) {
+	m.ops = append(m.ops, "return")
+	next(ctx, o)
+}
+
+func TestMiddlewares(t *testing.T) {
+	ctx := context.Background()
+
+	mw := &countingMiddleware{}
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.Middlewares[Foo](mw, pool.CreateTimeout[Foo](time.Second)),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+	f, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Invalidate(ctx, f)
+
+	assert.Equal(t, []string{"borrow", "create", "return", "borrow"}, mw.ops)
+	assert.EqualValues(t, 1, expired.Load())
+}
+
+func TestTickJanitor(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.MaxLifetime[Foo](20*time.Millisecond),
+		pool.IdleTimeout[Foo](2*time.Hour),
+		pool.JanitorSleep[Foo](time.Hour),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, p.TickJanitor(ctx))
+	assert.EqualValues(t, 1, expired.Load())
+
+	p.Close(ctx)
+	require.ErrorIs(t, p.TickJanitor(ctx), pool.ErrPoolClosed)
+}
+
+func TestDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+	)
+	require.NoError(t, err)
+
+	select {
+	case <-p.Done():
+		t.Fatal("pool should not be done")
+	default:
+	}
+
+	cancel()
+	select {
+	case <-p.Done():
+	case <-time.After(time.Second):
+		t.Fatal("pool should be done")
+	}
+
+	// already stopped
+	p.Close(context.Background())
+}
+
+func TestAccessors(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](3),
+		pool.MinIdle[Foo](2),
+	)
+	require.NoError(t, err)
+
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, p.Cap())
+	assert.Equal(t, 1, p.Idle())
+	assert.Equal(t, 1, p.InUse())
+	assert.False(t, p.IsClosed())
+
+	p.Close(ctx)
+	assert.True(t, p.IsClosed())
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := pool.NewConfig(
+		pool.Size[Foo](2),
+		pool.MinIdle[Foo](3),
+		pool.JanitorSleep[Foo](time.Minute),
+	)
+	err := cfg.Validate()
+	require.ErrorIs(t, err, pool.ErrInvalidConfig)
+	assert.ErrorContains(t, err, "min idle 3 is greater than the size 2")
+	assert.ErrorContains(t, err, "janitor sleep 1m0s must be less than the idle timeout 30s")
+
+	_, err = pool.New[Foo](
+		context.Background(),
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.BorrowTimeout[Foo](0),
+	)
+	require.ErrorIs(t, err, pool.ErrInvalidConfig)
+
+	require.NoError(t, pool.NewConfig[Foo]().Validate())
+}
+
+func TestLazy(t *testing.T) {
+	ctx := context.Background()
+
+	var fail atomic.Bool
+	fail.Store(true)
+	logged := make(chan error, 1)
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			if fail.Load() {
+				return nil, errors.New("database is down")
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](2),
+		pool.Lazy[Foo](),
+		pool.ErrLogger[Foo](func(ctx context.Context, err error, msg string) {
+			logged <- err
+		}),
+	)
+	require.NoError(t, err)
+
+	select {
+	case err := <-logged:
+		require.ErrorContains(t, err, "database is down")
+	case <-time.After(time.Second):
+		t.Fatal("warmup failure should be logged")
+	}
+
+	fail.Store(false)
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+}
+
+func TestFailover(t *testing.T) {
+	ctx := context.Background()
+
+	var primaryDown atomic.Bool
+	var primaryCalls atomic.Int32
+	primary := func(ctx context.Context) (*Foo, error) {
+		primaryCalls.Add(1)
+		if primaryDown.Load() {
+			return nil, errors.New("primary is down")
+		}
+		return &Foo{"primary"}, nil
+	}
+	replica := func(ctx context.Context) (*Foo, error) {
+		return &Foo{"replica"}, nil
+	}
+	create := pool.Failover(pool.FailoverPolicy{Failures: 2, Cooldown: 50 * time.Millisecond}, primary, replica)
+
+	f, err := create(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", f.name)
+
+	primaryDown.Store(true)
+	for range 3 {
+		f, err = create(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "replica", f.name)
+	}
+	// demoted after 2 failures
+	assert.EqualValues(t, 3, primaryCalls.Load())
+
+	// promoted back after the cooldown
+	primaryDown.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	f, err = create(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", f.name)
+}
+
+func TestSetCreate(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"old"}, nil },
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.Size[Foo](2),
+	)
+	require.NoError(t, err)
+
+	idle, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	borrowed, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, idle)
+
+	p.SetCreate(func(ctx context.Context) (*Foo, error) { return &Foo{"new"}, nil })
+
+	// the stale idle object is replaced
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "new", f.name)
+	assert.EqualValues(t, 1, expired.Load())
+
+	// the stale borrowed object is expired on return
+	p.Return(ctx, borrowed)
+	assert.EqualValues(t, 2, expired.Load())
+}
+
+func TestCanary(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"stable"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](100),
+		pool.Canary(func(ctx context.Context) (*Foo, error) { return &Foo{"canary"}, nil }, 1),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "canary", f.name)
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Invalidate(ctx, f)
+
+	baseline, canary := p.CanaryStats()
+	assert.Equal(t, pool.VariantStats{}, baseline)
+	assert.Equal(t, pool.VariantStats{Objects: 1, Created: 2, Invalidated: 1}, canary)
+}
+
+func TestCanaryQuarantined(t *testing.T) {
+	ctx := context.Background()
+
+	var invalid atomic.Bool
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"stable"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Canary(func(ctx context.Context) (*Foo, error) { return &Foo{"canary"}, nil }, 1),
+		pool.Validate(func(ctx context.Context, f *Foo) (bool, error) { return !invalid.Load(), nil }),
+		pool.Quarantine[Foo](1, time.Hour),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+
+	// the quarantined canary is still a canary object
+	invalid.Store(true)
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, p.Stats().Quarantined)
+
+	baseline, canary := p.CanaryStats()
+	assert.Equal(t, 0, baseline.Objects)
+	assert.Equal(t, 2, canary.Objects)
+}
+
+func TestValidationInterval(t *testing.T) {
+	ctx := context.Background()
+
+	var validations atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Validate(func(ctx context.Context, f *Foo) (bool, error) {
+			validations.Add(1)
+			return true, nil
+		}),
+		pool.ValidationInterval[Foo](50*time.Millisecond),
+		pool.Size[Foo](1),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+
+	// recently used
+	f, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+	assert.EqualValues(t, 0, validations.Load())
+
+	time.Sleep(60 * time.Millisecond)
+	f, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, validations.Load())
+
+	// handed off to a waiting borrower
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.Return(ctx, f)
+	}()
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, validations.Load())
+}
+
+func TestDeepValidate(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.MinIdle[Foo](2),
+		pool.IdleTimeout[Foo](2*time.Hour),
+		pool.JanitorSleep[Foo](time.Hour),
+		pool.DeepValidate(func(ctx context.Context, f *Foo) (bool, error) {
+			return f.name != "broken", nil
+		}),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	f.name = "broken"
+	p.Return(ctx, f)
+
+	require.NoError(t, p.TickJanitor(ctx))
+	assert.EqualValues(t, 1, expired.Load())
+	// the expired object is replaced
+	require.Eventually(t, func() bool { return p.Idle() == 2 }, time.Second, time.Millisecond)
+}
+
+func TestMaxBorrowValidations(t *testing.T) {
+	ctx := context.Background()
+
+	var validations atomic.Int32
+	var expired sync.WaitGroup
+	expired.Add(2)
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) { expired.Done() },
+		pool.Size[Foo](5),
+		pool.MinIdle[Foo](4),
+		pool.Validate(func(ctx context.Context, f *Foo) (bool, error) {
+			validations.Add(1)
+			return f.name == "", nil
+		}),
+		pool.MaxBorrowValidations[Foo](2),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", f.name)
+	assert.EqualValues(t, 2, validations.Load())
+	expired.Wait()
+	// the expired objects are replaced
+	require.Eventually(t, func() bool { return p.Idle() == 4 }, time.Second, time.Millisecond)
+}
+
+func TestValidationErrorExpire(t *testing.T) {
+	ctx := context.Background()
+
+	var logged atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](1),
+		pool.Validate(func(ctx context.Context, f *Foo) (bool, error) {
+			if f.name == "broken" {
+				return false, errors.New("connection reset")
+			}
+			return true, nil
+		}),
+		pool.OnValidationError[Foo](pool.ValidationErrorExpire),
+		pool.ErrLogger[Foo](func(ctx context.Context, err error, msg string) {
+			logged.Add(1)
+		}),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	f.name = "broken"
+	p.Return(ctx, f)
+
+	f, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", f.name)
+	assert.EqualValues(t, 1, logged.Load())
+}
+
+func TestReplenishOnInvalidate(t *testing.T) {
+	ctx := context.Background()
+
+	var created atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			created.Add(1)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](2),
+		pool.IdleTimeout[Foo](2*time.Hour),
+		pool.JanitorSleep[Foo](time.Hour),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Invalidate(ctx, f)
+
+	require.Eventually(t, func() bool { return p.Idle() == 2 }, time.Second, time.Millisecond)
+	assert.EqualValues(t, 3, created.Load())
+}
+
+func TestCleanUpReport(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](1),
+		pool.MaxLifetime[Foo](20*time.Millisecond),
+		pool.BorrowTimeout[Foo](20*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+	time.Sleep(30 * time.Millisecond)
+
+	report, err := p.CleanUpReport(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, pool.CleanUpReport{Evicted: 1, Reclaimed: 1, Created: 1}, report)
+}
+
+func TestLastSweepAndError(t *testing.T) {
+	ctx := context.Background()
+
+	var fail atomic.Bool
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			if fail.Load() {
+				return nil, errors.New("database is down")
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](1),
+		pool.ErrLogger[Foo](func(ctx context.Context, err error, msg string) {}),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, p.TickJanitor(ctx))
+	stats := p.Stats()
+	assert.False(t, stats.LastSweep.IsZero())
+	assert.NoError(t, stats.LastError)
+
+	fail.Store(true)
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	_, err = p.Borrow(ctx)
+	require.Error(t, err)
+
+	stats = p.Stats()
+	assert.ErrorContains(t, stats.LastError, "database is down")
+	assert.False(t, stats.LastErrorAt.IsZero())
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+
+	var fail atomic.Bool
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			if fail.Load() {
+				return nil, errors.New("database is down")
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+	)
+	require.NoError(t, err)
+	events := p.Events()
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+	fail.Store(true)
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	_, err = p.Borrow(ctx)
+	require.Error(t, err)
+	p.Close(ctx)
+
+	var types []pool.EventType
+	for e := range events {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []pool.EventType{
+		pool.EventCreated,
+		pool.EventBorrowed,
+		pool.EventReturned,
+		pool.EventBorrowed,
+		pool.EventCreateFailed,
+	}, types)
+}
+
+func TestWarnSlow(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var warnings []string
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			time.Sleep(20 * time.Millisecond)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+		pool.WarnSlowCreate[Foo](10*time.Millisecond),
+		pool.WarnSlowBorrowWait[Foo](10*time.Millisecond),
+		pool.WarnLogger[Foo](func(ctx context.Context, msg string, attrs ...slog.Attr) {
+			mu.Lock()
+			defer mu.Unlock()
+			warnings = append(warnings, msg)
+		}),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	time.AfterFunc(30*time.Millisecond, func() { p.Return(ctx, f) })
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"slow object creation", "slow borrow wait"}, warnings)
+}
+
+func TestWaitShare(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+		pool.WaitShare[Foo](0.5),
+	)
+	require.NoError(t, err)
+
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = p.Borrow(tctx)
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+	assert.Less(t, time.Since(start), 90*time.Millisecond)
+	require.NoError(t, tctx.Err())
+}
+
+func TestMaxOverflow(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.Size[Foo](1),
+		pool.MaxOverflow[Foo](1),
+	)
+	require.NoError(t, err)
+
+	a, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	b, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	_, err = p.Borrow(ctx, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	// the object beyond the size is expired on return
+	p.Return(ctx, b)
+	assert.EqualValues(t, 1, expired.Load())
+	p.Return(ctx, a)
+	assert.EqualValues(t, 1, expired.Load())
+	assert.Equal(t, 1, p.Idle())
+}
+
+func TestUnboundedSize(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](0),
+	)
+	require.NoError(t, err)
+
+	for range 100 {
+		_, err := p.Borrow(ctx, pool.WithNoWait())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, pool.Stats{Size: 0, InUse: 100}, gauges(p.Stats()))
+	assert.Equal(t, 0, p.Cap())
+}
+
+func TestPeaks(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+	)
+	require.NoError(t, err)
+
+	a, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	b, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = p.Borrow(tctx)
+	require.Error(t, err)
+	p.Return(ctx, a)
+	p.Return(ctx, b)
+
+	assert.Equal(t, pool.Peaks{InUse: 2, Idle: 2, Waiters: 1}, p.Stats().Peaks)
+
+	p.ResetPeaks()
+	assert.Equal(t, pool.Peaks{Idle: 2}, p.Stats().Peaks)
+}
+
+func TestCost(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Cost(func(f *Foo) int64 { return int64(len(f.name)) }),
+	)
+	require.NoError(t, err)
+
+	a, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	b, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	b.name = "longer"
+	p.Return(ctx, b)
+
+	stats := p.Stats()
+	assert.EqualValues(t, 6, stats.IdleCost)
+	assert.EqualValues(t, len(a.name), stats.InUseCost)
+}
+
+func TestStatsString(t *testing.T) {
+	stats := pool.Stats{Size: 5, Idle: 2, InUse: 1, Peaks: pool.Peaks{InUse: 3}, LastError: errors.New("boom")}
+
+	assert.Equal(t, `size=5 idle=2 in_use=1 waiters=0 peak_idle=0 peak_in_use=3 peak_waiters=0 last_error="boom" last_error_at=0001-01-01T00:00:00Z`, stats.String())
+
+	b, err := json.Marshal(pool.Stats{Size: 5, Idle: 2, InUse: 1, Peaks: pool.Peaks{InUse: 3}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"size":5,"idle":2,"in_use":1,"waiters":0,"peaks":{"in_use":3,"idle":0,"waiters":0}}`, string(b))
+}
+
+func TestLabelStats(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx, pool.WithLabel("checkout"))
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	p.Return(ctx, f)
+	_, err = p.Borrow(ctx, pool.WithLabel("checkout"))
+	require.NoError(t, err)
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+
+	labels := p.Stats().Labels
+	require.Len(t, labels, 1)
+	checkout := labels["checkout"]
+	assert.Equal(t, 1, checkout.InUse)
+	assert.Equal(t, 2, checkout.Borrows)
+	assert.GreaterOrEqual(t, checkout.HoldTime, 10*time.Millisecond)
+}
+
+func TestLongestHolders(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.DebugStacks[Foo](),
+	)
+	require.NoError(t, err)
+
+	first, err := p.Borrow(ctx, pool.WithLabel("first"))
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = p.Borrow(ctx, pool.WithLabel("second"))
+	require.NoError(t, err)
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+
+	holders := p.LongestHolders(2)
+	require.Len(t, holders, 2)
+	assert.Same(t, first, holders[0].Object)
+	assert.Equal(t, "first", holders[0].Label)
+	assert.Equal(t, "second", holders[1].Label)
+	assert.Contains(t, holders[0].Stack, "TestLongestHolders")
+}
+
+func TestStrict(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Strict[Foo](),
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+	assert.PanicsWithValue(t, "pool: return of an object that is not borrowed", func() { p.Return(ctx, f) })
+	assert.PanicsWithValue(t, "pool: return of an object that does not belong to the pool", func() { p.Return(ctx, &Foo{}) })
+
+	f, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Invalidate(ctx, f)
+	assert.PanicsWithValue(t, "pool: invalidate of an expired object", func() { p.Invalidate(ctx, f) })
+
+	// only the last expired objects are remembered
+	for range 1024 {
+		o, err := p.Borrow(ctx)
+		require.NoError(t, err)
+		p.Invalidate(ctx, o)
+	}
+	assert.PanicsWithValue(t, "pool: invalidate of an object that does not belong to the pool", func() { p.Invalidate(ctx, f) })
+
+	p.Close(ctx)
+	assert.PanicsWithValue(t, "pool: borrow on a closed pool", func() { _, _ = p.Borrow(ctx) })
+}
+
+func TestReentrantBorrow(t *testing.T) {
+	ctx := context.Background()
+
+	var logged atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+		pool.DebugStacks[Foo](),
+		pool.ErrLogger[Foo](func(ctx context.Context, err error, msg string) {
+			assert.ErrorContains(t, err, "TestReentrantBorrow")
+			logged.Add(1)
+		}),
+	)
+	require.NoError(t, err)
+
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = p.Borrow(tctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualValues(t, 1, logged.Load())
+}
+
+func TestStateOf(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+	)
+	require.NoError(t, err)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, pool.StateBorrowed, p.StateOf(f))
+	p.Return(ctx, f)
+	assert.Equal(t, pool.StateIdle, p.StateOf(f))
+
+	f, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Invalidate(ctx, f)
+	assert.Equal(t, pool.StateUnknown, p.StateOf(f))
+	assert.Equal(t, pool.StateUnknown, p.StateOf(&Foo{}))
+}
+
+func TestOnAbandoned(t *testing.T) {
+	ctx := context.Background()
+
+	newPool := func(policy pool.AbandonedPolicy, warnings *atomic.Int32) *pool.Pool[Foo] {
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+			func(ctx context.Context, f *Foo) {},
+			pool.BorrowTimeout[Foo](10*time.Millisecond),
+			pool.OnAbandoned[Foo](policy),
+			pool.WarnLogger[Foo](func(ctx context.Context, msg string, attrs ...slog.Attr) {
+				warnings.Add(1)
+			}),
+		)
+		require.NoError(t, err)
+		_, err = p.Borrow(ctx)
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		return p
+	}
+
+	var warnings atomic.Int32
+	p := newPool(pool.AbandonedLogOnly, &warnings)
+	for range 2 {
+		report, err := p.CleanUpReport(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, pool.CleanUpReport{Abandoned: 1}, report)
+	}
+	assert.EqualValues(t, 1, warnings.Load())
+	assert.Equal(t, 1, p.InUse())
+
+	p = newPool(pool.AbandonedReclaimAndReplace, &warnings)
+	report, err := p.CleanUpReport(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, pool.CleanUpReport{Reclaimed: 1}, report)
+	assert.Equal(t, 0, p.InUse())
+	assert.Equal(t, 1, p.Idle())
+}
+
+func TestReplaceOnExpire(t *testing.T) {
+	ctx := context.Background()
+
+	var created atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			created.Add(1)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+		pool.ReplaceOnExpire[Foo](),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	a, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	b, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	// the borrowed object is replaced by an idle one
+	p.Invalidate(ctx, a)
+	require.Eventually(t, func() bool { return p.Idle() == 1 }, time.Second, 5*time.Millisecond)
+	assert.EqualValues(t, 3, created.Load())
+
+	// no replacement beyond the size
+	p.Return(ctx, b)
+	c, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Invalidate(ctx, c)
+	require.Eventually(t, func() bool { return p.Idle() == 2 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualValues(t, 4, created.Load())
+	assert.Equal(t, 2, p.Idle())
+}
+
+func TestWarmupConcurrency(t *testing.T) {
+	ctx := context.Background()
+
+	var running, peak atomic.Int32
+	create := func(ctx context.Context) (*Foo, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := peak.Load()
+			if n <= m || peak.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return &Foo{"foo"}, nil
+	}
+
+	p, err := pool.New[Foo](
+		ctx,
+		create,
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](10),
+		pool.MinIdle[Foo](8),
+		pool.WarmupConcurrency[Foo](4),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	assert.Equal(t, 8, p.Idle())
+	assert.EqualValues(t, 4, peak.Load())
+
+	_, err = pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return nil, errors.New("boom") },
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](3),
+		pool.WarmupConcurrency[Foo](2),
+	)
+	require.ErrorContains(t, err, "boom")
+}
+
+func TestExpiryJitter(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](20),
+		pool.JanitorSleep[Foo](5*time.Millisecond),
+		pool.IdleTimeout[Foo](10*time.Millisecond),
+		pool.ExpiryJitter[Foo](200*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	objects := make([]*Foo, 20)
+	for i := range objects {
+		objects[i], err = p.Borrow(ctx)
+		require.NoError(t, err)
+	}
+	for _, o := range objects {
+		p.Return(ctx, o)
+	}
+
+	// objects created together expire over time, instead of all at once
+	time.Sleep(30 * time.Millisecond)
+	assert.Positive(t, p.Idle())
+	require.Eventually(t, func() bool { return p.Idle() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestRemoveIdle(t *testing.T) {
+	ctx := context.Background()
+
+	var expired []string
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{}, nil },
+		func(ctx context.Context, f *Foo) { expired = append(expired, f.name) },
+		pool.Size[Foo](4),
+		pool.MinIdle[Foo](1),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	objects := make([]*Foo, 4)
+	for i := range objects {
+		objects[i], err = p.Borrow(ctx)
+		require.NoError(t, err)
+		objects[i].name = strconv.Itoa(i)
+	}
+	for _, o := range objects {
+		p.Return(ctx, o)
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, 2, p.RemoveIdle(ctx, 2))
+	assert.Equal(t, []string{"0", "1"}, expired)
+	// never below the min idle
+	assert.Equal(t, 1, p.RemoveIdle(ctx, 5))
+	assert.Equal(t, 1, p.Idle())
+	assert.Equal(t, 0, p.RemoveIdle(ctx, 5))
+}
+
+func TestMemoryPressure(t *testing.T) {
+	ctx := context.Background()
+
+	pressure := make(chan struct{})
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](8),
+		pool.MinIdle[Foo](2),
+		pool.MemoryPressure[Foo](pressure),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	objects := make([]*Foo, 8)
+	for i := range objects {
+		objects[i], err = p.Borrow(ctx)
+		require.NoError(t, err)
+	}
+	for _, o := range objects {
+		p.Return(ctx, o)
+	}
+
+	// half of the idle objects beyond the min idle are shed on each signal
+	pressure <- struct{}{}
+	require.Eventually(t, func() bool { return p.Idle() == 5 }, time.Second, time.Millisecond)
+	pressure <- struct{}{}
+	require.Eventually(t, func() bool { return p.Idle() == 3 }, time.Second, time.Millisecond)
+	close(pressure)
+}
+
+func TestMemoryLimitPressure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pressure := pool.MemoryLimitPressure(ctx, 0.9, time.Millisecond)
+	select {
+	case <-pressure:
+		t.Fatal("unexpected pressure without a memory limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(1))
+	select {
+	case <-pressure:
+	case <-time.After(time.Second):
+		t.Fatal("expected pressure above the memory limit")
+	}
+}
+
+func TestCreateParking(t *testing.T) {
+	ctx := context.Background()
+
+	unblock := make(chan struct{})
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			<-unblock
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = p.Borrow(tctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	// the pool is not blocked by the create
+	assert.Equal(t, 1, p.Stats().Creating)
+
+	// the object created for the borrower that gave up is parked idle
+	close(unblock)
+	require.Eventually(t, func() bool { return p.Idle() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, 0, p.Stats().Creating)
+
+	_, lease, err := p.BorrowLease(ctx)
+	require.NoError(t, err)
+	assert.True(t, lease.Reused)
+}
+
+func TestPrecreate(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](10),
+		pool.Precreate[Foo](2, time.Second, 4),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	for range 2 {
+		_, err := p.Borrow(ctx)
+		require.NoError(t, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, p.Idle())
+
+	// too many misses in the interval
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return p.Idle() == 4 }, time.Second, time.Millisecond)
+	assert.Equal(t, 3, p.InUse())
+}
+
+func TestFreeze(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := p.Borrow(ctx)
+		waiter <- err
+	}()
+	require.Eventually(t, func() bool { return p.Stats().Waiters == 1 }, time.Second, time.Millisecond)
+
+	p.Freeze("drain")
+	var maintenance pool.ErrMaintenance
+	require.ErrorAs(t, <-waiter, &maintenance)
+	assert.Equal(t, "drain", maintenance.Reason)
+	_, err = p.Borrow(ctx)
+	require.ErrorAs(t, err, &maintenance)
+
+	// returns still work
+	p.Return(ctx, f)
+	assert.Equal(t, 1, p.Idle())
+
+	p.Unfreeze()
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+}
+
+func TestSoftClose(t *testing.T) {
+	ctx := context.Background()
+
+	var created atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			created.Add(1)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](2),
+	)
+	require.NoError(t, err)
+
+	a, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	b, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, a)
+
+	p.SoftClose()
+
+	// idle objects are still served, but no object is created
+	a, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = p.Borrow(tctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualValues(t, 2, created.Load())
+
+	// closed once drained
+	p.Return(ctx, b)
+	p.Invalidate(ctx, a)
+	assert.False(t, p.IsClosed())
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Invalidate(ctx, b)
+	select {
+	case <-p.Done():
+	case <-time.After(time.Second):
+		t.Fatal("pool not closed")
+	}
+	assert.True(t, p.IsClosed())
+}
+
+func TestTransferIdleTo(t *testing.T) {
+	ctx := context.Background()
+
+	newPool := func(size int) *pool.Pool[Foo] {
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+			func(ctx context.Context, f *Foo) {},
+			pool.Size[Foo](size),
+			pool.MinIdle[Foo](3),
+		)
+		require.NoError(t, err)
+		return p
+	}
+	src := newPool(3)
+	defer src.Close(ctx)
+	dst := newPool(4)
+	defer dst.Close(ctx)
+
+	// only one fits in the destination
+	n, err := src.TransferIdleTo(ctx, dst, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 4, dst.Idle())
+	require.Eventually(t, func() bool { return src.Idle() == 3 }, time.Second, time.Millisecond)
+
+	dst.Close(ctx)
+	_, err = src.TransferIdleTo(ctx, dst, 2)
+	require.ErrorIs(t, err, pool.ErrPoolClosed)
+	assert.Equal(t, 3, src.Idle())
+}
+
+func TestRotator(t *testing.T) {
+	ctx := context.Background()
+
+	newPool := func(name string) *pool.Pool[Foo] {
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{name}, nil },
+			func(ctx context.Context, f *Foo) {},
+			pool.Register[Foo](nil),
+		)
+		require.NoError(t, err)
+		return p
+	}
+	blue, green := newPool("blue"), newPool("green")
+	r := pool.NewRotator(blue)
+	defer r.Close(ctx)
+
+	a, err := r.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "blue", a.name)
+
+	r.Rotate(green)
+	assert.Same(t, green, r.Current())
+	b, err := r.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "green", b.name)
+
+	// the old pool is closed once its objects are drained
+	r.Invalidate(ctx, a)
+	r.Return(ctx, b)
+	select {
+	case <-blue.Done():
+	case <-time.After(time.Second):
+		t.Fatal("old pool not closed")
+	}
+	assert.Equal(t, 1, green.Idle())
+}
+
+func TestCleanup(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+
+	fail := false
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			pool.Cleanup(ctx, func(context.Context) { record("close connection") })
+			pool.Cleanup(ctx, func(context.Context) { record("end session") })
+			if fail {
+				return nil, errors.New("boom")
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) { record("expire") },
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Invalidate(ctx, f)
+	assert.Equal(t, []string{"expire", "end session", "close connection"}, calls)
+
+	// the cleanups also run when the creation fails
+	calls = nil
+	fail = true
+	_, err = p.Borrow(ctx)
+	require.Error(t, err)
+	assert.Equal(t, []string{"end session", "close connection"}, calls)
+}
+
+func TestDependsOn(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+
+	a, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"a"}, nil },
+		func(ctx context.Context, f *Foo) { record("expire a") },
+		pool.MinIdle[Foo](1),
+	)
+	require.NoError(t, err)
+	b, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"b"}, nil },
+		func(ctx context.Context, f *Foo) { record("expire b") },
+		pool.MinIdle[Foo](1),
+		pool.DependsOn[Foo](a),
+	)
+	require.NoError(t, err)
+
+	// closing the parent closes the dependent first
+	a.Close(ctx)
+	assert.True(t, b.IsClosed())
+	assert.Equal(t, []string{"expire b", "expire a"}, calls)
+
+	_, err = pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"c"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.DependsOn[Foo](a),
+	)
+	require.ErrorIs(t, err, pool.ErrPoolClosed)
+}
+
+func TestWaitReady(t *testing.T) {
+	ctx := context.Background()
+
+	var validations atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			time.Sleep(10 * time.Millisecond)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.MinIdle[Foo](2),
+		pool.Lazy[Foo](),
+		pool.Validate[Foo](func(ctx context.Context, f *Foo) (bool, error) {
+			// the first validation fails
+			return validations.Add(1) > 1, nil
+		}),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, p.WaitReady(ctx))
+	assert.GreaterOrEqual(t, p.Idle(), 2)
+	assert.EqualValues(t, 2, validations.Load())
+
+	p.Close(ctx)
+	require.ErrorIs(t, p.WaitReady(ctx), pool.ErrPoolClosed)
+}
+
+func TestRequireInitialObject(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts, expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			if attempts.Add(1) < 3 {
+				return nil, errors.New("flaky")
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.RequireInitialObject[Foo](3, time.Millisecond),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.Idle())
+	p.Close(ctx)
+	assert.EqualValues(t, 1, expired.Load())
+
+	attempts.Store(0)
+	_, err = pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			attempts.Add(1)
+			return nil, errors.New("down")
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.RequireInitialObject[Foo](2, time.Millisecond),
+		pool.Lazy[Foo](),
+	)
+	require.ErrorContains(t, err, "down")
+	assert.EqualValues(t, 2, attempts.Load())
+}
+
+func TestBaseContext(t *testing.T) {
+	type key string
+	base := context.WithValue(context.Background(), key("tenant"), "acme")
+
+	var mu sync.Mutex
+	seen := map[string][]any{}
+	record := func(callback string, ctx context.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, hasDeadline := ctx.Deadline()
+		seen[callback] = []any{ctx.Value(key("tenant")), ctx.Value(key("request")), hasDeadline}
+	}
+
+	ctx := context.Background()
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			record("create", ctx)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) { record("expire", ctx) },
+		pool.BaseContext[Foo](base),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	rctx, cancel := context.WithTimeout(context.WithValue(ctx, key("request"), "r1"), time.Second)
+	defer cancel()
+	f, err := p.Borrow(rctx)
+	require.NoError(t, err)
+	p.Invalidate(rctx, f)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []any{"acme", nil, false}, seen["create"])
+	assert.Equal(t, []any{"acme", nil, true}, seen["expire"])
+}
+
+func TestUse(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	name, err := pool.Use(ctx, p, func(ctx context.Context, f *Foo) (string, error) {
+		return f.name, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "foo", name)
+	assert.Equal(t, 1, p.Idle())
+
+	_, err = pool.Use(ctx, p, func(ctx context.Context, f *Foo) (string, error) {
+		return "", fmt.Errorf("broken connection: %w", pool.ErrInvalidObject)
+	})
+	require.ErrorIs(t, err, pool.ErrInvalidObject)
+	assert.Equal(t, 0, p.Idle())
+	assert.EqualValues(t, 1, expired.Load())
+
+	assert.Panics(t, func() {
+		_, _ = pool.Use(ctx, p, func(ctx context.Context, f *Foo) (int, error) {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 0, p.Stats().InUse)
+	assert.EqualValues(t, 2, expired.Load())
+}
+
+func TestEach(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](3),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	// up to the available objects
+	var calls atomic.Int32
+	err = pool.Each(ctx, p, 5, func(ctx context.Context, f *Foo) error {
+		calls.Add(1)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, calls.Load())
+	assert.Equal(t, 3, p.Idle())
+
+	// the first error cancels the others
+	boom := fmt.Errorf("boom: %w", pool.ErrInvalidObject)
+	var cancelled atomic.Int32
+	err = pool.Each(ctx, p, 3, func(ctx context.Context, f *Foo) error {
+		if calls.Add(1) == 4 {
+			return boom
+		}
+		<-ctx.Done()
+		cancelled.Add(1)
+		return nil
+	})
+	require.ErrorIs(t, err, boom)
+	assert.EqualValues(t, 2, cancelled.Load())
+	assert.Equal(t, 2, p.Idle())
+	assert.Equal(t, 0, p.InUse())
+}
+
+func TestBorrowWaitTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+		pool.WaitTimeout[Foo](20*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = p.Borrow(ctx)
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+	assert.ErrorContains(t, err, "wait timeout")
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// the caller context still applies
+	tctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	_, err = p.Borrow(tctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFailFast(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	var down atomic.Bool
+	down.Store(true)
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			attempts.Add(1)
+			if down.Load() {
+				return nil, errors.New("boom")
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.FailFast[Foo](2, 30*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	for range 2 {
+		_, err = p.Borrow(ctx)
+		require.ErrorContains(t, err, "boom")
+	}
+	assert.False(t, p.Healthy())
+	_, err = p.Borrow(ctx)
+	require.ErrorIs(t, err, pool.ErrUnhealthy)
+	assert.EqualValues(t, 2, attempts.Load())
+
+	// a creation is tried again after the cooldown
+	down.Store(false)
+	time.Sleep(40 * time.Millisecond)
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.True(t, p.Healthy())
+}
+
+func TestJournal(t *testing.T) {
+	ctx := context.Background()
+
+	var fail atomic.Bool
+	fail.Store(true)
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			if fail.Swap(false) {
+				return nil, errors.New("boom")
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Journal[Foo](3),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	types := func() []pool.EventType {
+		var types []pool.EventType
+		for _, e := range p.Stats().Journal {
+			types = append(types, e.Type)
+		}
+		return types
+	}
+
+	_, err = p.Borrow(ctx)
+	require.Error(t, err)
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+	p.RemoveIdle(ctx, 1)
+	assert.Equal(t, []pool.EventType{pool.EventCreateFailed, pool.EventCreated, pool.EventEvicted}, types())
+	assert.EqualError(t, p.Stats().Journal[0].Err, "boom")
+
+	// the oldest events are overwritten
+	_, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []pool.EventType{pool.EventCreated, pool.EventEvicted, pool.EventCreated}, types())
+
+	b, err := json.Marshal(p.Stats())
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"journal":[{"type":"created"`)
+}
+
+func TestStatsHistory(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.JanitorSleep[Foo](time.Hour),
+		pool.IdleTimeout[Foo](2*time.Hour),
+		pool.StatsHistory[Foo](2),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	assert.Empty(t, p.History())
+	for i := range 3 {
+		if i > 0 {
+			_, err := p.Borrow(ctx)
+			require.NoError(t, err)
+		}
+		require.NoError(t, p.TickJanitor(ctx))
+	}
+
+	history := p.History()
+	require.Len(t, history, 2)
+	assert.Equal(t, 1, history[0].Stats.InUse)
+	assert.Equal(t, 2, history[1].Stats.InUse)
+	assert.False(t, history[1].Time.Before(history[0].Time))
+}
+
+func TestWaiting(t *testing.T) {
+	ctx := context.Background()
+
+	strategies := map[string]pool.WaitStrategy{
+		"spin":      pool.NewSpinCond(time.Millisecond),
+		"handoff":   pool.NewHandoffCond(),
+		"semaphore": pool.NewSemaCond(),
+	}
+	for name, strategy := range strategies {
+		t.Run(name, func(t *testing.T) {
+			p, err := pool.New[Foo](
+				ctx,
+				func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+				func(ctx context.Context, f *Foo) {},
+				pool.Size[Foo](1),
+				pool.Waiting[Foo](strategy),
+			)
+			require.NoError(t, err)
+			defer p.Close(ctx)
+
+			f, err := p.Borrow(ctx)
+			require.NoError(t, err)
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				p.Return(ctx, f)
+			}()
+			f2, err := p.Borrow(ctx)
+			require.NoError(t, err)
+			assert.Same(t, f, f2)
+		})
+	}
+}
+
+// gatedCond counts the broadcasts and holds the woken up waiters until the gate is opened
+type gatedCond struct {
+	*pool.Cond
+	broadcasts atomic.Int32
+	gate       chan struct{}
+}
+
+func (c *gatedCond) WaitSeq(ctx context.Context, seq uint64) error {
+	if err := c.Cond.WaitSeq(ctx, seq); err != nil {
+		return err
+	}
+	<-c.gate
+	return nil
+}
+
+func (c *gatedCond) Broadcast() {
+	c.broadcasts.Add(1)
+	c.Cond.Broadcast()
+}
+
+func TestBroadcastSuppression(t *testing.T) {
+	ctx := context.Background()
+
+	cond := &gatedCond{Cond: pool.NewCond(), gate: make(chan struct{})}
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](5),
+		pool.Waiting[Foo](cond),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	var objs []*Foo
+	for range 5 {
+		f, err := p.Borrow(ctx)
+		require.NoError(t, err)
+		objs = append(objs, f)
+	}
+
+	done := make(chan error)
+	go func() {
+		f, err := p.Borrow(ctx)
+		if err == nil {
+			p.Return(ctx, f)
+		}
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return p.Stats().Waiters == 1 }, time.Second, time.Millisecond)
+
+	// a burst of returns wakes up the waiter only once
+	for _, f := range objs {
+		p.Return(ctx, f)
+	}
+	assert.EqualValues(t, 1, cond.broadcasts.Load())
+
+	close(cond.gate)
+	require.NoError(t, <-done)
+}
+
+func TestOnBorrowAbandoned(t *testing.T) {
+	ctx := context.Background()
+
+	type abandonment struct {
+		waited   time.Duration
+		position int
+	}
+	abandoned := make(chan abandonment, 1)
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+		pool.OnBorrowAbandoned[Foo](func(ctx context.Context, waited time.Duration, position int) {
+			abandoned <- abandonment{waited, position}
+		}),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	// the first waiter stays ahead in the queue
+	first, cancelFirst := context.WithCancel(ctx)
+	defer cancelFirst()
+	done := make(chan error)
+	go func() {
+		_, err := p.Borrow(first)
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return p.Stats().Waiters == 1 }, time.Second, time.Millisecond)
+
+	second, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = p.Borrow(second)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	a := <-abandoned
+	assert.Equal(t, 2, a.position)
+	assert.GreaterOrEqual(t, a.waited, 20*time.Millisecond)
+
+	cancelFirst()
+	require.ErrorIs(t, <-done, context.Canceled)
+	a = <-abandoned
+	assert.Equal(t, 1, a.position)
+
+	p.Return(ctx, f)
+}
+
+func TestIdentity(t *testing.T) {
+	ctx := context.Background()
+
+	var count atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			return &Foo{fmt.Sprintf("conn-%d", count.Add(1))}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+		pool.Identity[Foo](func(f *Foo) string { return f.name }),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	events := p.Events()
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+
+	infos := p.Inspect()
+	require.Len(t, infos, 1)
+	assert.Equal(t, "conn-1", infos[0].ID)
+	assert.Equal(t, "conn-1", p.LongestHolders(1)[0].ID)
+
+	p.Return(ctx, f)
+	for _, want := range []pool.EventType{pool.EventCreated, pool.EventBorrowed, pool.EventReturned} {
+		e := <-events
+		assert.Equal(t, want, e.Type)
+		assert.Equal(t, "conn-1", e.Object)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	var expired atomic.Int32
+	newPool := func(size int) *pool.Pool[Foo] {
+		var count atomic.Int32
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) {
+				return &Foo{fmt.Sprintf("token-%d", count.Add(1))}, nil
+			},
+			func(ctx context.Context, f *Foo) { expired.Add(1) },
+			pool.Size[Foo](size),
+			pool.MinIdle[Foo](2),
+		)
+		require.NoError(t, err)
+		return p
+	}
+
+	src := newPool(2)
+	data, err := src.Export(ctx, func(f *Foo) ([]byte, error) { return []byte(f.name), nil })
+	require.NoError(t, err)
+	assert.Len(t, data, 2)
+	assert.Equal(t, 0, src.Stats().Idle)
+	src.Close(ctx)
+	assert.EqualValues(t, 0, expired.Load(), "exported objects are not expired")
+
+	dst := newPool(3)
+	defer dst.Close(ctx)
+	// the destination already has its min idle objects, so only one fits
+	n, err := dst.Import(ctx, data, func(ctx context.Context, b []byte) (*Foo, error) { return &Foo{string(b)}, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.EqualValues(t, 1, expired.Load(), "objects that do not fit are expired")
+	assert.Equal(t, 3, dst.Stats().Idle)
+}
+
+// localPermits shares a fixed number of permits between pools
+type localPermits struct {
+	mu   sync.Mutex
+	free int
+}
+
+func (l *localPermits) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.free == 0 {
+		return false, nil
+	}
+	l.free--
+	return true, nil
+}
+
+func (l *localPermits) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.free++
+	return nil
+}
+
+func TestGlobalPermits(t *testing.T) {
+	ctx := context.Background()
+
+	permits := &localPermits{free: 2}
+	newPool := func() *pool.Pool[Foo] {
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+			func(ctx context.Context, f *Foo) {},
+			pool.Size[Foo](2),
+			pool.GlobalPermits[Foo](permits, 5*time.Millisecond),
+		)
+		require.NoError(t, err)
+		return p
+	}
+	p1 := newPool()
+	defer p1.Close(ctx)
+	p2 := newPool()
+	defer p2.Close(ctx)
+
+	f1, err := p1.Borrow(ctx)
+	require.NoError(t, err)
+	f2, err := p1.Borrow(ctx)
+	require.NoError(t, err)
+
+	// the other pool has room, but there are no permits left
+	_, err = p2.Borrow(ctx, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	done := make(chan error)
+	go func() {
+		f, err := p2.Borrow(ctx)
+		if err == nil {
+			p2.Return(ctx, f)
+		}
+		done <- err
+	}()
+
+	// an expired object gives back its permit to the waiting borrower
+	p1.Invalidate(ctx, f1)
+	require.NoError(t, <-done)
+	p1.Return(ctx, f2)
+	assert.Equal(t, 0, permits.free)
+}
+
+func TestRegistryAggregateStats(t *testing.T) {
+	ctx := context.Background()
+
+	registry := pool.NewRegistry()
+	newPool := func(name, backend string) *pool.Pool[Foo] {
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+			func(ctx context.Context, f *Foo) {},
+			pool.Name[Foo](name),
+			pool.Attributes[Foo](slog.String("backend", backend)),
+			pool.Register[Foo](registry),
+		)
+		require.NoError(t, err)
+		return p
+	}
+	a := newPool("a", "db")
+	defer a.Close(ctx)
+	b := newPool("b", "db")
+	defer b.Close(ctx)
+	c := newPool("c", "cache")
+	defer c.Close(ctx)
+
+	_, err := a.Borrow(ctx, pool.WithLabel("api"))
+	require.NoError(t, err)
+	_, err = b.Borrow(ctx, pool.WithLabel("api"))
+	require.NoError(t, err)
+
+	total := registry.AggregateStats()
+	assert.Equal(t, 15, total.Size)
+	assert.Equal(t, 2, total.InUse)
+	assert.Equal(t, 2, total.Labels["api"].InUse)
+
+	perBackend := map[string]pool.Stats{}
+	registry.ForEach(func(p pool.Registered) {
+		for _, attr := range p.Attributes() {
+			if attr.Key == "backend" {
+				perBackend[attr.Value.String()] = perBackend[attr.Value.String()].Add(p.Stats())
+			}
+		}
+	})
+	assert.Equal(t, 2, perBackend["db"].InUse)
+	assert.Equal(t, 10, perBackend["db"].Size)
+	assert.Equal(t, 0, perBackend["cache"].InUse)
+}
+
+func TestServeStale(t *testing.T) {
+	ctx := context.Background()
+
+	var failing atomic.Bool
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			if failing.Load() {
+				return nil, errors.New("backend down")
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.Size[Foo](1),
+		pool.Validate(func(ctx context.Context, f *Foo) (bool, error) { return !failing.Load(), nil }),
+		pool.ServeStale[Foo](),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+
+	// the object fails validation and no object can be created, so it is served stale
+	failing.Store(true)
+	stale, lease, err := p.BorrowLease(ctx)
+	require.NoError(t, err)
+	assert.Same(t, f, stale)
+	assert.True(t, lease.Degraded)
+	p.Return(ctx, stale)
+
+	// the degraded object is kept apart from the idle ones
+	assert.Equal(t, 0, p.Stats().Idle)
+	assert.Equal(t, 1, p.Stats().Degraded)
+	assert.Equal(t, pool.StateDegraded, p.StateOf(f))
+	infos := p.Inspect()
+	require.Len(t, infos, 1)
+	assert.Equal(t, pool.StateDegraded, infos[0].State)
+	all, err := p.BorrowAllIdle(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+	assert.Equal(t, 0, p.RemoveIdle(ctx, 1))
+
+	// once objects can be created again, the degraded object is skipped and expired
+	failing.Store(false)
+	fresh, lease, err := p.BorrowLease(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, f, fresh)
+	assert.False(t, lease.Degraded)
+	assert.EqualValues(t, 1, expired.Load())
+	p.Return(ctx, fresh)
+}
+
+func TestQuarantine(t *testing.T) {
+	ctx := context.Background()
+
+	var blip atomic.Bool
+	var created, expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			created.Add(1)
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.Size[Foo](2),
+		pool.JanitorSleep[Foo](10*time.Millisecond),
+		pool.Validate(func(ctx context.Context, f *Foo) (bool, error) { return !blip.Load(), nil }),
+		pool.Quarantine[Foo](1, time.Minute),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+
+	// the object fails validation during the blip, so it is quarantined and a new one is created
+	blip.Store(true)
+	g, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, f, g)
+	assert.Equal(t, 1, p.Stats().Quarantined)
+	assert.Equal(t, pool.StateQuarantined, p.StateOf(f))
+	assert.Len(t, p.Inspect(), 2)
+	p.Return(ctx, g)
+
+	// after the blip, the quarantined object is rescued by the janitor
+	blip.Store(false)
+	require.Eventually(t, func() bool { return p.Stats().Quarantined == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, 2, p.Stats().Idle)
+	assert.EqualValues(t, 2, created.Load())
+	assert.EqualValues(t, 0, expired.Load())
+}
+
+func TestCloseExpiresWithItsContext(t *testing.T) {
+	type key struct{}
+	ctx := context.Background()
+
+	for range 100 {
+		var errs atomic.Int32
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+			func(ctx context.Context, f *Foo) {
+				if ctx.Err() != nil || ctx.Value(key{}) == nil {
+					errs.Add(1)
+				}
+			},
+			pool.MinIdle[Foo](2),
+		)
+		require.NoError(t, err)
+
+		p.Close(context.WithValue(ctx, key{}, true))
+		require.Zero(t, errs.Load(), "idle objects must be expired with the context given to Close")
+	}
+}
+
+func TestCreateGrace(t *testing.T) {
+	ctx := context.Background()
+
+	var hang atomic.Bool
+	hang.Store(true)
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) {
+			if hang.Load() {
+				<-ctx.Done()
+				return nil, context.Cause(ctx)
+			}
+			return &Foo{"foo"}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Size[Foo](1),
+		pool.CreateGrace[Foo](20*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	borrowCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = p.Borrow(borrowCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, p.Stats().Creating)
+
+	// the hung creation is cancelled after the grace, giving back its slot
+	hang.Store(false)
+	require.Eventually(t, func() bool { return p.Stats().Creating == 0 }, time.Second, time.Millisecond)
+	f, err := p.Borrow(ctx, pool.WithNoWait())
+	require.NoError(t, err)
+	p.Return(ctx, f)
+}
+
+func TestQuarantineReclaimDuringRescue(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		valid = iota
+		invalid
+		gated
+	)
+	var mode atomic.Int32
+	rescuing := make(chan struct{}, 1)
+	gate := make(chan struct{})
+	var expired atomic.Int32
+	p, err := pool.New[Foo](
+		ctx,
+		func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+		func(ctx context.Context, f *Foo) { expired.Add(1) },
+		pool.Size[Foo](2),
+		pool.JanitorSleep[Foo](5*time.Millisecond),
+		pool.BorrowTimeout[Foo](50*time.Millisecond),
+		pool.Validate(func(ctx context.Context, f *Foo) (bool, error) {
+			switch mode.Load() {
+			case invalid:
+				return false, nil
+			case gated:
+				select {
+				case rescuing <- struct{}{}:
+				default:
+				}
+				<-gate
+			}
+			return true, nil
+		}),
+		pool.Quarantine[Foo](1, time.Minute),
+	)
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+
+	mode.Store(invalid)
+	g, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, g)
+	require.Equal(t, 1, p.Stats().Quarantined)
+
+	// the quarantined object has been around for longer than the borrow timeout
+	time.Sleep(60 * time.Millisecond)
+	mode.Store(gated)
+	<-rescuing
+
+	// it is not reclaimed while being validated
+	require.NoError(t, p.CleanUp(ctx))
+	close(gate)
+	require.Eventually(t, func() bool { return p.Stats().Quarantined == 0 && p.Stats().InUse == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, 2, p.Stats().Idle)
+	assert.EqualValues(t, 0, expired.Load())
+}
+
+func TestKeyedCloseExpiresWithItsContext(t *testing.T) {
+	type key struct{}
+	ctx := context.Background()
+
+	for range 100 {
+		var errs atomic.Int32
+		kp := pool.NewKeyed[string, Foo](
+			ctx,
+			func(ctx context.Context, key string) (*Foo, error) { return &Foo{key}, nil },
+			func(ctx context.Context, f *Foo) {
+				if ctx.Err() != nil || ctx.Value(key{}) == nil {
+					errs.Add(1)
+				}
+			},
+		)
+		for _, k := range []string{"a", "b"} {
+			f, err := kp.Borrow(ctx, k)
+			require.NoError(t, err)
+			kp.Return(ctx, k, f)
+		}
+
+		kp.Close(context.WithValue(ctx, key{}, true))
+		require.Zero(t, errs.Load(), "idle objects must be expired with the context given to Close")
+	}
+}
@@ -0,0 +1,148 @@
+// Package poolbuf provides byte slice pooling bucketed by size classes.
+// Unlike sync.Pool, idle buffers are released after the idle timeout and the number of buffers is bounded.
+package poolbuf
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/quintans/pool"
+)
+
+// Buffer is the pooled object.
+type Buffer struct {
+	B []byte
+	// origin is the size class the buffer was borrowed from, nil if it is not pooled
+	origin *class
+}
+
+type Option func(*config)
+
+type config struct {
+	classes []int
+	options []pool.Option[Buffer]
+}
+
+// Classes sets the capacities of the size classes.
+func Classes(sizes ...int) Option {
+	return func(c *config) {
+		c.classes = sizes
+	}
+}
+
+// PoolOptions sets the options of the pool of each size class.
+func PoolOptions(options ...pool.Option[Buffer]) Option {
+	return func(c *config) {
+		c.options = append(c.options, options...)
+	}
+}
+
+type class struct {
+	size int
+	pool *pool.Pool[Buffer]
+}
+
+type Pool struct {
+	classes []class
+}
+
+// New creates a buffer pool with a pool per size class.
+// By default a buffer held by the caller is never reclaimed by the borrow timeout.
+func New(ctx context.Context, options ...Option) (*Pool, error) {
+	cfg := config{
+		classes: []int{512, 2 << 10, 8 << 10, 32 << 10, 128 << 10, 512 << 10, 2 << 20},
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	sizes := append([]int(nil), cfg.classes...)
+	sort.Ints(sizes)
+
+	p := &Pool{}
+	for _, size := range sizes {
+		if size < 1 || (len(p.classes) > 0 && p.classes[len(p.classes)-1].size == size) {
+			continue
+		}
+
+		create := func(context.Context) (*Buffer, error) {
+			return &Buffer{B: make([]byte, 0, size)}, nil
+		}
+		// the buffer is left to the garbage collector, since it may still be held by the caller
+		expire := func(context.Context, *Buffer) {}
+		opts := append([]pool.Option[Buffer]{
+			pool.Size[Buffer](1024),
+			pool.BorrowTimeout[Buffer](time.Duration(math.MaxInt64)),
+		}, cfg.options...)
+		cp, err := pool.New(ctx, create, expire, opts...)
+		if err != nil {
+			p.Close(ctx)
+			return nil, fmt.Errorf("creating pool for size class %d: %w", size, err)
+		}
+		p.classes = append(p.classes, class{size: size, pool: cp})
+	}
+
+	return p, nil
+}
+
+// Get returns an empty buffer with a capacity of at least sizeHint.
+// Buffers bigger than the largest class are allocated and not pooled.
+func (p *Pool) Get(ctx context.Context, sizeHint int) (*Buffer, error) {
+	c := p.class(sizeHint)
+	if c == nil {
+		return &Buffer{B: make([]byte, 0, sizeHint)}, nil
+	}
+
+	b, err := c.pool.Borrow(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting buffer of size %d: %w", sizeHint, err)
+	}
+	b.origin = c
+	return b, nil
+}
+
+// Put returns the buffer to the size class it was borrowed from.
+// Buffers whose capacity no longer matches their size class, because they grew, are invalidated to free their slot.
+// Buffers that are not pooled are dropped.
+func (p *Pool) Put(ctx context.Context, b *Buffer) {
+	if b == nil || b.origin == nil {
+		return
+	}
+	c := b.origin
+	if cap(b.B) != c.size {
+		c.pool.Invalidate(ctx, b)
+		return
+	}
+
+	b.B = b.B[:0]
+	c.pool.Return(ctx, b)
+}
+
+// Close closes the pools of every size class.
+func (p *Pool) Close(ctx context.Context) {
+	for _, c := range p.classes {
+		c.pool.Close(ctx)
+	}
+}
+
+// Class returns the pool of the size class serving buffers of the given size, or nil if there is none.
+func (p *Pool) Class(size int) *pool.Pool[Buffer] {
+	c := p.class(size)
+	if c == nil {
+		return nil
+	}
+	return c.pool
+}
+
+func (p *Pool) class(size int) *class {
+	i := sort.Search(len(p.classes), func(i int) bool {
+		return p.classes[i].size >= size
+	})
+	if i == len(p.classes) {
+		return nil
+	}
+	return &p.classes[i]
+}
@@ -0,0 +1,57 @@
+package poolbuf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quintans/pool/poolbuf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPut(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := poolbuf.New(ctx, poolbuf.Classes(1024, 64))
+	require.NoError(t, err)
+
+	b, err := p.Get(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(b.B))
+	assert.Equal(t, 1024, cap(b.B))
+
+	b.B = append(b.B, "hello"...)
+	p.Put(ctx, b)
+
+	b2, err := p.Get(ctx, 1000)
+	require.NoError(t, err)
+	assert.Same(t, b, b2)
+	assert.Equal(t, 0, len(b2.B))
+
+	small, err := p.Get(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 64, cap(small.B))
+
+	big, err := p.Get(ctx, 4096)
+	require.NoError(t, err)
+	assert.Equal(t, 4096, cap(big.B))
+	assert.Nil(t, p.Class(4096))
+}
+
+func TestPutGrownBuffer(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := poolbuf.New(ctx, poolbuf.Classes(64))
+	require.NoError(t, err)
+	defer p.Close(ctx)
+
+	b, err := p.Get(ctx, 10)
+	require.NoError(t, err)
+	b.B = append(b.B, make([]byte, 100)...)
+	p.Put(ctx, b)
+
+	// the grown buffer gives back its slot, instead of holding it until reclaimed
+	stats := p.Class(10).Stats()
+	assert.Equal(t, 0, stats.InUse)
+	assert.Equal(t, 0, stats.Idle)
+}
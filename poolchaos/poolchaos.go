@@ -0,0 +1,129 @@
+// Package poolchaos provides a fault injection middleware, to test how services behave when the pool misbehaves.
+package poolchaos
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/quintans/pool"
+)
+
+var ErrInjected = errors.New("injected failure")
+
+// Config sets the faults to inject. Rates are probabilities between 0 and 1.
+type Config struct {
+	// Seed makes the injected faults reproducible
+	Seed uint64
+	// CreateFailureRate is the probability of failing the creation of an object
+	CreateFailureRate float64
+	// ValidateFailureRate is the probability of failing the validation of an object with an error
+	ValidateFailureRate float64
+	// ExpireRate is the probability of a returned object dying while idle, being expired on its next validation
+	ExpireRate float64
+	// Latency is the maximum random latency added to creating and borrowing an object
+	Latency time.Duration
+}
+
+// Chaos is a pool middleware injecting faults.
+// The validation faults require the validate function to be wrapped with Validate.
+type Chaos[T any] struct {
+	mutex  sync.Mutex
+	cfg    Config
+	rnd    *rand.Rand
+	doomed map[*T]struct{}
+}
+
+var _ pool.Middleware[struct{}] = (*Chaos[struct{}])(nil)
+
+func New[T any](cfg Config) *Chaos[T] {
+	return &Chaos[T]{
+		cfg:    cfg,
+		rnd:    rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		doomed: map[*T]struct{}{},
+	}
+}
+
+func (c *Chaos[T]) Create(ctx context.Context, next func(context.Context) (*T, error)) (*T, error) {
+	if err := c.delay(ctx); err != nil {
+		return nil, err
+	}
+	if c.hit(c.cfg.CreateFailureRate) {
+		return nil, ErrInjected
+	}
+	return next(ctx)
+}
+
+func (c *Chaos[T]) Expire(ctx context.Context, o *T, next func(context.Context, *T)) {
+	c.mutex.Lock()
+	delete(c.doomed, o)
+	c.mutex.Unlock()
+
+	next(ctx, o)
+}
+
+func (c *Chaos[T]) Borrow(ctx context.Context, next func(context.Context) (*T, error)) (*T, error) {
+	if err := c.delay(ctx); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+func (c *Chaos[T]) Return(ctx context.Context, o *T, next func(context.Context, *T)) {
+	if c.hit(c.cfg.ExpireRate) {
+		c.mutex.Lock()
+		c.doomed[o] = struct{}{}
+		c.mutex.Unlock()
+	}
+	next(ctx, o)
+}
+
+// Validate wraps the validate function of the pool, injecting validation failures and early expirations.
+func (c *Chaos[T]) Validate(validate func(context.Context, *T) (bool, error)) func(context.Context, *T) (bool, error) {
+	return func(ctx context.Context, o *T) (bool, error) {
+		c.mutex.Lock()
+		_, doomed := c.doomed[o]
+		c.mutex.Unlock()
+		if doomed {
+			return false, nil
+		}
+		if c.hit(c.cfg.ValidateFailureRate) {
+			return false, ErrInjected
+		}
+		if validate == nil {
+			return true, nil
+		}
+		return validate(ctx, o)
+	}
+}
+
+func (c *Chaos[T]) hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.rnd.Float64() < rate
+}
+
+func (c *Chaos[T]) delay(ctx context.Context) error {
+	if c.cfg.Latency <= 0 {
+		return nil
+	}
+
+	c.mutex.Lock()
+	d := time.Duration(c.rnd.Int64N(int64(c.cfg.Latency)))
+	c.mutex.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
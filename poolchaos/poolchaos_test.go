@@ -0,0 +1,74 @@
+package poolchaos_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quintans/pool"
+	"github.com/quintans/pool/poolchaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Foo struct {
+	id int
+}
+
+func newPool(t *testing.T, c *poolchaos.Chaos[Foo]) *pool.Pool[Foo] {
+	t.Helper()
+
+	ids := 0
+	p, err := pool.New[Foo](
+		context.Background(),
+		func(ctx context.Context) (*Foo, error) {
+			ids++
+			return &Foo{ids}, nil
+		},
+		func(ctx context.Context, f *Foo) {},
+		pool.Middlewares[Foo](c),
+		pool.Validate(c.Validate(nil)),
+		pool.Register[Foo](nil),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { p.Close(context.Background()) })
+	return p
+}
+
+func TestCreateFailure(t *testing.T) {
+	p := newPool(t, poolchaos.New[Foo](poolchaos.Config{CreateFailureRate: 1}))
+
+	_, err := p.Borrow(context.Background())
+	require.ErrorIs(t, err, poolchaos.ErrInjected)
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	ctx := context.Background()
+	p := newPool(t, poolchaos.New[Foo](poolchaos.Config{ExpireRate: 1, Latency: time.Millisecond}))
+
+	f, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, f)
+
+	f, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.id)
+}
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+
+	failures := func() []bool {
+		p := newPool(t, poolchaos.New[Foo](poolchaos.Config{Seed: 42, CreateFailureRate: 0.5}))
+		var res []bool
+		for range 20 {
+			f, err := p.Borrow(ctx)
+			res = append(res, err != nil)
+			if err == nil {
+				p.Invalidate(ctx, f)
+			}
+		}
+		return res
+	}
+	assert.Equal(t, failures(), failures())
+}
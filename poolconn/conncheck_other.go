@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || solaris || illumos)
+
+package poolconn
+
+import "net"
+
+func connCheck(net.Conn) error {
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package poolconn provides a pool of network connections.
+package poolconn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/quintans/pool"
+)
+
+// Conn is the pooled object, holding a network connection.
+type Conn struct {
+	net.Conn
+}
+
+type Option func(*config)
+
+type config struct {
+	dialTimeout time.Duration
+	keepAlive   time.Duration
+	options     []pool.Option[Conn]
+}
+
+// DialTimeout sets the maximum amount of time a dial will wait for a connect to complete.
+func DialTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.dialTimeout = d
+	}
+}
+
+// KeepAlive sets the interval between TCP keep-alive probes. A negative value disables them.
+func KeepAlive(d time.Duration) Option {
+	return func(c *config) {
+		c.keepAlive = d
+	}
+}
+
+// PoolOptions sets the options of the underlying pool.
+func PoolOptions(options ...pool.Option[Conn]) Option {
+	return func(c *config) {
+		c.options = append(c.options, options...)
+	}
+}
+
+// New creates a pool of connections dialed to the given network address.
+// Borrowed connections are checked for having been closed by the remote peer.
+func New(ctx context.Context, network, addr string, options ...Option) (*pool.Pool[Conn], error) {
+	cfg := config{
+		dialTimeout: 5 * time.Second,
+		keepAlive:   15 * time.Second,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.dialTimeout,
+		KeepAlive: cfg.keepAlive,
+	}
+
+	create := func(ctx context.Context) (*Conn, error) {
+		c, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, fmt.Errorf("dialing %s %s: %w", network, addr, err)
+		}
+		return &Conn{Conn: c}, nil
+	}
+	expire := func(_ context.Context, c *Conn) {
+		_ = c.Close()
+	}
+
+	opts := append([]pool.Option[Conn]{pool.Validate(Validate)}, cfg.options...)
+	return pool.New(ctx, create, expire, opts...)
+}
+
+// Validate checks if the connection was closed by the remote peer,
+// by doing a non blocking read on the underlying socket.
+// A connection with unread data is also considered invalid, since that data would not belong to the next borrower.
+func Validate(_ context.Context, c *Conn) (bool, error) {
+	return connCheck(c.Conn) == nil, nil
+}
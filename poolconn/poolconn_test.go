@@ -0,0 +1,52 @@
+package poolconn_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/quintans/pool/poolconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteCloseDetection(t *testing.T) {
+	ctx := context.Background()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	p, err := poolconn.New(ctx, "tcp", l.Addr().String(), poolconn.DialTimeout(time.Second))
+	require.NoError(t, err)
+
+	c1, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, c1)
+
+	c2, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Same(t, c1, c2)
+	p.Return(ctx, c2)
+
+	// remote peer closes the connection
+	server := <-accepted
+	require.NoError(t, server.Close())
+	time.Sleep(100 * time.Millisecond)
+
+	c3, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, c1, c3)
+}
@@ -0,0 +1,23 @@
+module github.com/quintans/pool/poolgrpc
+
+go 1.25.0
+
+require (
+	github.com/quintans/pool v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.9.0
+	google.golang.org/grpc v1.84.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	golang.org/x/time v0.10.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/quintans/pool => ../
@@ -0,0 +1,145 @@
+// Package poolgrpc provides a pool of gRPC client connections,
+// spreading the RPCs over several connections to avoid hitting the HTTP/2 concurrent streams limit.
+package poolgrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/quintans/pool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Conn is the pooled object, holding a gRPC client connection.
+type Conn struct {
+	*grpc.ClientConn
+}
+
+// New creates a pool of gRPC client connections to the target.
+func New(ctx context.Context, target string, dialOptions []grpc.DialOption, options ...pool.Option[Conn]) (*pool.Pool[Conn], error) {
+	create := func(context.Context) (*Conn, error) {
+		cc, err := grpc.NewClient(target, dialOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("creating grpc client for %s: %w", target, err)
+		}
+		return &Conn{ClientConn: cc}, nil
+	}
+
+	options = append([]pool.Option[Conn]{pool.Validate(Validate)}, options...)
+	return pool.New(ctx, create, expire, options...)
+}
+
+func expire(_ context.Context, c *Conn) {
+	_ = c.Close()
+}
+
+// Validate checks the connectivity state of the connection.
+// Connections that are shutdown or in transient failure are considered invalid.
+// Idle connections are asked to connect.
+func Validate(_ context.Context, c *Conn) (bool, error) {
+	switch c.GetState() {
+	case connectivity.Shutdown, connectivity.TransientFailure:
+		return false, nil
+	case connectivity.Idle:
+		c.Connect()
+	}
+	return true, nil
+}
+
+// UnaryClientInterceptor returns an interceptor that invokes the RPC on a connection borrowed from the pool,
+// ignoring the connection the interceptor was registered on.
+func UnaryClientInterceptor(p *pool.Pool[Conn]) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, _ *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		c, err := p.Borrow(ctx)
+		if err != nil {
+			return err
+		}
+		defer p.Return(ctx, c)
+
+		return invoker(ctx, method, req, reply, c.ClientConn, opts...)
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that opens the stream on a connection borrowed from the pool,
+// ignoring the connection the interceptor was registered on.
+// The connection is returned when the stream ends.
+func StreamClientInterceptor(p *pool.Pool[Conn]) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, _ *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return newStream(ctx, p, func(c *Conn) (grpc.ClientStream, error) {
+			return streamer(ctx, desc, c.ClientConn, method, opts...)
+		})
+	}
+}
+
+// ClientConn implements grpc.ClientConnInterface, borrowing a connection from the pool for each RPC.
+// It can be passed directly to the generated client constructors.
+type ClientConn struct {
+	pool *pool.Pool[Conn]
+}
+
+var _ grpc.ClientConnInterface = (*ClientConn)(nil)
+
+func NewClientConn(p *pool.Pool[Conn]) *ClientConn {
+	return &ClientConn{pool: p}
+}
+
+func (c *ClientConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	cc, err := c.pool.Borrow(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.pool.Return(ctx, cc)
+
+	return cc.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (c *ClientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return newStream(ctx, c.pool, func(cc *Conn) (grpc.ClientStream, error) {
+		return cc.NewStream(ctx, desc, method, opts...)
+	})
+}
+
+func newStream(ctx context.Context, p *pool.Pool[Conn], open func(*Conn) (grpc.ClientStream, error)) (grpc.ClientStream, error) {
+	c, err := p.Borrow(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := open(c)
+	if err != nil {
+		p.Return(ctx, c)
+		return nil, err
+	}
+
+	ps := &stream{ClientStream: s}
+	ps.release = func() {
+		ps.once.Do(func() {
+			p.Return(context.Background(), c)
+		})
+	}
+	// the stream is also terminated when the context is done
+	go func() {
+		<-s.Context().Done()
+		ps.release()
+	}()
+
+	return ps, nil
+}
+
+// stream returns the connection to the pool once the stream ends.
+type stream struct {
+	grpc.ClientStream
+	once    sync.Once
+	release func()
+}
+
+func (s *stream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		s.release()
+	}
+	return err
+}
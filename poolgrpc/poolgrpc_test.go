@@ -0,0 +1,47 @@
+package poolgrpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/quintans/pool"
+	"github.com/quintans/pool/poolgrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestClientConnReusesConnections(t *testing.T) {
+	ctx := context.Background()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(l)
+	defer server.Stop()
+
+	p, err := poolgrpc.New(
+		ctx,
+		l.Addr().String(),
+		[]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		pool.Size[poolgrpc.Conn](2),
+	)
+	require.NoError(t, err)
+
+	client := healthpb.NewHealthClient(poolgrpc.NewClientConn(p))
+	for i := 0; i < 3; i++ {
+		res, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+	}
+
+	idle, err := p.BorrowAllIdle(ctx)
+	require.NoError(t, err)
+	assert.Len(t, idle, 1)
+}
@@ -0,0 +1,123 @@
+// Package poolredis provides global permits backed by Redis,
+// so that the pools of several replicas share the max connections of a backend.
+//
+// Each replica keeps its count of permits in a hash and refreshes a liveness key,
+// so that the permits of a replica that died without releasing them are reclaimed once its liveness key expires.
+// The keys of a set of permits must be in the same Redis node.
+package poolredis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/quintans/pool"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ pool.Permits = (*Permits)(nil)
+
+// acquire takes a permit if the live replicas hold less than the max, forgetting the dead replicas.
+// KEYS[1] is the hash of the permits per replica and ARGV is the max, the replica and its liveness ttl in milliseconds.
+var acquire = redis.NewScript(`
+local total = 0
+local counts = redis.call('HGETALL', KEYS[1])
+for i = 1, #counts, 2 do
+	local replica = counts[i]
+	if replica ~= ARGV[2] and redis.call('EXISTS', KEYS[1] .. ':' .. replica) == 0 then
+		redis.call('HDEL', KEYS[1], replica)
+	else
+		total = total + tonumber(counts[i + 1])
+	end
+end
+redis.call('SET', KEYS[1] .. ':' .. ARGV[2], 1, 'PX', ARGV[3])
+if total >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], ARGV[2], 1)
+return 1
+`)
+
+// release gives back a permit of the replica.
+// KEYS[1] is the hash of the permits per replica and ARGV is the replica and its liveness ttl in milliseconds.
+var release = redis.NewScript(`
+redis.call('SET', KEYS[1] .. ':' .. ARGV[1], 1, 'PX', ARGV[2])
+if redis.call('HINCRBY', KEYS[1], ARGV[1], -1) <= 0 then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// Permits are global permits shared by the replicas using the same key.
+type Permits struct {
+	client  redis.UniversalClient
+	key     string
+	max     int
+	ttl     time.Duration
+	replica string
+}
+
+// New creates the permits of this replica, allowing up to max permits among all the replicas using the key.
+// The liveness of the replica is refreshed every third of the ttl, until the context is done.
+func New(ctx context.Context, client redis.UniversalClient, key string, max int, ttl time.Duration) (*Permits, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("generating replica id: %w", err)
+	}
+	p := &Permits{
+		client:  client,
+		key:     key,
+		max:     max,
+		ttl:     ttl,
+		replica: hex.EncodeToString(id),
+	}
+	if err := p.refresh(ctx); err != nil {
+		return nil, err
+	}
+	go p.heartbeat(ctx)
+	return p, nil
+}
+
+// Acquire takes a permit, returning false if all of them are taken.
+func (p *Permits) Acquire(ctx context.Context) (bool, error) {
+	ok, err := acquire.Run(ctx, p.client, []string{p.key}, p.max, p.replica, p.ttl.Milliseconds()).Bool()
+	if err != nil {
+		return false, fmt.Errorf("acquiring permit %s: %w", p.key, err)
+	}
+	return ok, nil
+}
+
+// Release gives back a permit.
+func (p *Permits) Release(ctx context.Context) error {
+	err := release.Run(ctx, p.client, []string{p.key}, p.replica, p.ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("releasing permit %s: %w", p.key, err)
+	}
+	return nil
+}
+
+// refresh keeps the replica alive
+func (p *Permits) refresh(ctx context.Context) error {
+	err := p.client.Set(ctx, p.key+":"+p.replica, 1, p.ttl).Err()
+	if err != nil {
+		return fmt.Errorf("refreshing permits %s: %w", p.key, err)
+	}
+	return nil
+}
+
+func (p *Permits) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(p.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// a failed refresh is retried on the next tick, before the ttl expires
+			_ = p.refresh(ctx)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package poolredis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/quintans/pool/poolredis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermitsSharedByReplicas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	p1, err := poolredis.New(ctx, client, "permits", 2, time.Minute)
+	require.NoError(t, err)
+	p2, err := poolredis.New(ctx, client, "permits", 2, time.Minute)
+	require.NoError(t, err)
+
+	for _, p := range []*poolredis.Permits{p1, p2} {
+		ok, err := p.Acquire(ctx)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, err := p1.Acquire(ctx)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, p2.Release(ctx))
+	ok, err = p1.Acquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestPermitsOfDeadReplicaAreReclaimed(t *testing.T) {
+	ctx := context.Background()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	deadCtx, kill := context.WithCancel(ctx)
+	dead, err := poolredis.New(deadCtx, client, "permits", 1, time.Second)
+	require.NoError(t, err)
+	ok, err := dead.Acquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	// the replica dies without releasing its permit
+	kill()
+
+	alive, err := poolredis.New(ctx, client, "permits", 1, time.Minute)
+	require.NoError(t, err)
+	ok, err = alive.Acquire(ctx)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	server.FastForward(2 * time.Second)
+	ok, err = alive.Acquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
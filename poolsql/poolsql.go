@@ -0,0 +1,211 @@
+// Package poolsql exposes a pool of driver connections through a driver.Connector,
+// so that a *sql.DB can be used while keeping the pool eviction and minIdle semantics.
+//
+// Since *sql.DB keeps its own idle connections, it should be configured with
+// db.SetMaxIdleConns(0), so that every released connection goes back to the pool.
+// Connections found bad are expired instead, and closing the *sql.DB closes the pool.
+package poolsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/quintans/pool"
+)
+
+// Conn is the pooled object, holding a driver connection.
+type Conn struct {
+	driver.Conn
+}
+
+type Connector struct {
+	connector driver.Connector
+	pool      *pool.Pool[Conn]
+}
+
+var (
+	_ driver.Connector = (*Connector)(nil)
+	_ io.Closer        = (*Connector)(nil)
+)
+
+// New creates a connector where the connections are created by the given connector and managed by the pool.
+func New(ctx context.Context, connector driver.Connector, options ...pool.Option[Conn]) (*Connector, error) {
+	c := &Connector{
+		connector: connector,
+	}
+
+	options = append([]pool.Option[Conn]{pool.Validate(validate)}, options...)
+	p, err := pool.New(ctx, c.create, expire, options...)
+	if err != nil {
+		return nil, fmt.Errorf("creating sql connection pool: %w", err)
+	}
+	c.pool = p
+
+	return c, nil
+}
+
+func (c *Connector) create(ctx context.Context) (*Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn}, nil
+}
+
+func expire(_ context.Context, c *Conn) {
+	_ = c.Close()
+}
+
+func validate(ctx context.Context, c *Conn) (bool, error) {
+	if v, ok := c.Conn.(driver.Validator); ok && !v.IsValid() {
+		return false, nil
+	}
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		if err := r.ResetSession(ctx); err != nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Pool returns the underlying pool
+func (c *Connector) Pool() *pool.Pool[Conn] {
+	return c.pool
+}
+
+// Connect borrows a connection from the pool. Closing the returned connection returns it to the pool.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	o, err := c.pool.Borrow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{pooled: o, pool: c.pool}, nil
+}
+
+func (c *Connector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// Close closes the pool. It is called by sql.DB.Close.
+func (c *Connector) Close() error {
+	c.pool.Close(context.Background())
+	return nil
+}
+
+// conn forwards the calls to the pooled connection, except Close that returns it to the pool,
+// or invalidates it if it went bad.
+type conn struct {
+	pooled *Conn
+	pool   *pool.Pool[Conn]
+	bad    bool
+}
+
+var (
+	_ driver.Conn               = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+)
+
+// check flags the connection as bad if the driver says so
+func (c *conn) check(err error) error {
+	if errors.Is(err, driver.ErrBadConn) {
+		c.bad = true
+	}
+	return err
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.pooled.Prepare(query)
+	return stmt, c.check(err)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if pc, ok := c.pooled.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err := pc.PrepareContext(ctx, query)
+		return stmt, c.check(err)
+	}
+	return c.Prepare(query)
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	tx, err := c.pooled.Begin()
+	return tx, c.check(err)
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.pooled.Conn.(driver.ConnBeginTx); ok {
+		tx, err := b.BeginTx(ctx, opts)
+		return tx, c.check(err)
+	}
+	if opts.ReadOnly || opts.Isolation != 0 {
+		return nil, errors.New("poolsql: driver does not support non-default transaction options")
+	}
+	return c.Begin()
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.pooled.Conn.(driver.ExecerContext); ok {
+		res, err := e.ExecContext(ctx, query, args)
+		return res, c.check(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.pooled.Conn.(driver.QueryerContext); ok {
+		rows, err := q.QueryContext(ctx, query, args)
+		return rows, c.check(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.pooled.Conn.(driver.Pinger); ok {
+		return c.check(p.Ping(ctx))
+	}
+	return nil
+}
+
+// ResetSession resets the pooled connection, flagging it as bad if it fails.
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.pooled.Conn.(driver.SessionResetter); ok {
+		if err := r.ResetSession(ctx); err != nil {
+			c.bad = true
+			return err
+		}
+	}
+	return nil
+}
+
+// IsValid reports if the connection is not bad, according to the driver.
+func (c *conn) IsValid() bool {
+	if v, ok := c.pooled.Conn.(driver.Validator); ok && !v.IsValid() {
+		c.bad = true
+	}
+	return !c.bad
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.pooled.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *conn) Close() error {
+	if !c.IsValid() {
+		c.pool.Invalidate(context.Background(), c.pooled)
+		return nil
+	}
+	c.pool.Return(context.Background(), c.pooled)
+	return nil
+}
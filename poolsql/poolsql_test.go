@@ -0,0 +1,106 @@
+package poolsql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+	"testing"
+
+	"github.com/quintans/pool"
+	"github.com/quintans/pool/poolsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConnector struct {
+	created atomic.Int32
+	closed  atomic.Int32
+	bad     atomic.Bool
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	c.created.Add(1)
+	return &fakeConn{connector: c}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	connector *fakeConnector
+	execs     int
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *fakeConn) Close() error {
+	c.connector.closed.Add(1)
+	return nil
+}
+
+func (c *fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if c.connector.bad.Swap(false) {
+		return nil, driver.ErrBadConn
+	}
+	c.execs++
+	return driver.RowsAffected(c.execs), nil
+}
+
+func TestConnectorReusesPooledConnections(t *testing.T) {
+	ctx := context.Background()
+
+	fake := &fakeConnector{}
+	connector, err := poolsql.New(ctx, fake, pool.Size[poolsql.Conn](2))
+	require.NoError(t, err)
+
+	db := sql.OpenDB(connector)
+	db.SetMaxIdleConns(0)
+	defer db.Close()
+
+	for i := 1; i <= 3; i++ {
+		res, err := db.ExecContext(ctx, "UPDATE foo SET bar = 1")
+		require.NoError(t, err)
+		n, err := res.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(i), n)
+	}
+
+	assert.Equal(t, int32(1), fake.created.Load())
+	assert.Equal(t, int32(0), fake.closed.Load())
+}
+
+func TestConnectorInvalidatesBadConnections(t *testing.T) {
+	ctx := context.Background()
+
+	fake := &fakeConnector{}
+	connector, err := poolsql.New(ctx, fake, pool.Size[poolsql.Conn](2))
+	require.NoError(t, err)
+
+	db := sql.OpenDB(connector)
+	db.SetMaxIdleConns(0)
+
+	_, err = db.ExecContext(ctx, "UPDATE foo SET bar = 1")
+	require.NoError(t, err)
+
+	// the bad connection is expired and sql.DB retries with a new one
+	fake.bad.Store(true)
+	_, err = db.ExecContext(ctx, "UPDATE foo SET bar = 1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fake.created.Load())
+	assert.Equal(t, int32(1), fake.closed.Load())
+	assert.Equal(t, 1, connector.Pool().Stats().Idle)
+
+	// closing the db closes the pool
+	require.NoError(t, db.Close())
+	assert.Equal(t, int32(2), fake.closed.Load())
+	_, err = connector.Pool().Borrow(ctx)
+	require.ErrorIs(t, err, pool.ErrPoolClosed)
+}
@@ -0,0 +1,151 @@
+// Package pooltest provides a scriptable test double for pool.Borrower.
+package pooltest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/quintans/pool"
+)
+
+// Fake is an in memory pool.Borrower whose behaviour can be scripted by the test.
+type Fake[T any] struct {
+	mutex       sync.Mutex
+	cond        *pool.Cond
+	create      func() *T
+	size        int
+	exhausted   bool
+	createErr   error
+	latency     time.Duration
+	idle        []*T
+	borrowed    map[*T]struct{}
+	borrows     int
+	returns     int
+	invalidates int
+}
+
+var _ pool.Borrower[struct{}] = (*Fake[struct{}])(nil)
+
+// NewFake creates a fake with the given size, using create to build new objects.
+func NewFake[T any](size int, create func() *T) *Fake[T] {
+	return &Fake[T]{
+		cond:     pool.NewCond(),
+		create:   create,
+		size:     size,
+		borrowed: map[*T]struct{}{},
+	}
+}
+
+// SetExhausted makes Borrow wait, or fail with WithNoWait, as if there were no available objects, until it is cleared.
+func (f *Fake[T]) SetExhausted(exhausted bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.exhausted = exhausted
+	f.cond.Broadcast()
+}
+
+// SetCreateError makes Borrow fail with err when a new object would be created. Nil clears it.
+func (f *Fake[T]) SetCreateError(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.createErr = err
+}
+
+// SetLatency delays every Borrow by d.
+func (f *Fake[T]) SetLatency(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.latency = d
+}
+
+// Borrow borrows an object like the pool, waiting for a Return or an Invalidate when the size is reached.
+// Of the borrow options, only WithNoWait is honoured.
+func (f *Fake[T]) Borrow(ctx context.Context, options ...pool.BorrowOption) (*T, error) {
+	f.mutex.Lock()
+	latency := f.latency
+	f.mutex.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("on borrow: %w", ctx.Err())
+		case <-time.After(latency):
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for f.exhausted || len(f.borrowed) >= f.size {
+		if pool.IsNoWait(options...) {
+			return nil, fmt.Errorf("on borrow: %w", pool.ErrPoolExhausted)
+		}
+		// the sequence is taken while holding the lock, so that a Return happening before waiting is not missed
+		seq := f.cond.Sequence()
+		f.mutex.Unlock()
+		err := f.cond.WaitSeq(ctx, seq)
+		f.mutex.Lock()
+		if err != nil {
+			return nil, fmt.Errorf("on borrow while waiting: %w", err)
+		}
+	}
+
+	var o *T
+	if n := len(f.idle); n > 0 {
+		o = f.idle[n-1]
+		f.idle = f.idle[:n-1]
+	} else {
+		if f.createErr != nil {
+			return nil, fmt.Errorf("on borrow: %w", f.createErr)
+		}
+		o = f.create()
+	}
+	f.borrows++
+	f.borrowed[o] = struct{}{}
+	return o, nil
+}
+
+func (f *Fake[T]) Return(_ context.Context, o *T) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, ok := f.borrowed[o]; !ok {
+		return
+	}
+	f.returns++
+	delete(f.borrowed, o)
+	f.idle = append(f.idle, o)
+	f.cond.Broadcast()
+}
+
+func (f *Fake[T]) Invalidate(_ context.Context, o *T) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, ok := f.borrowed[o]; !ok {
+		return
+	}
+	f.invalidates++
+	delete(f.borrowed, o)
+	f.cond.Broadcast()
+}
+
+func (f *Fake[T]) Stats() pool.Stats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return pool.Stats{
+		Size:  f.size,
+		Idle:  len(f.idle),
+		InUse: len(f.borrowed),
+	}
+}
+
+// Calls returns how many successful borrows, returns and invalidations were made.
+func (f *Fake[T]) Calls() (borrows, returns, invalidates int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.borrows, f.returns, f.invalidates
+}
@@ -0,0 +1,86 @@
+package pooltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quintans/pool"
+	"github.com/quintans/pool/pooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Foo struct{}
+
+func TestFake(t *testing.T) {
+	ctx := context.Background()
+
+	var b pool.Borrower[Foo] = pooltest.NewFake(1, func() *Foo { return &Foo{} })
+	f := b.(*pooltest.Fake[Foo])
+
+	o, err := b.Borrow(ctx)
+	require.NoError(t, err)
+
+	// size reached
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = b.Borrow(tctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	b.Invalidate(ctx, o)
+
+	errCreate := errors.New("boom")
+	f.SetCreateError(errCreate)
+	_, err = b.Borrow(ctx)
+	require.ErrorIs(t, err, errCreate)
+
+	f.SetCreateError(nil)
+	o, err = b.Borrow(ctx)
+	require.NoError(t, err)
+	b.Return(ctx, o)
+	assert.Equal(t, pool.Stats{Size: 1, Idle: 1}, b.Stats())
+
+	borrows, returns, invalidates := f.Calls()
+	assert.Equal(t, 2, borrows)
+	assert.Equal(t, 1, returns)
+	assert.Equal(t, 1, invalidates)
+}
+
+func TestFakeWaits(t *testing.T) {
+	ctx := context.Background()
+
+	f := pooltest.NewFake(1, func() *Foo { return &Foo{} })
+
+	o, err := f.Borrow(ctx)
+	require.NoError(t, err)
+
+	_, err = f.Borrow(ctx, pool.WithNoWait())
+	require.ErrorIs(t, err, pool.ErrPoolExhausted)
+
+	// a return wakes up the waiting borrower
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		f.Return(ctx, o)
+	}()
+	o2, err := f.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Same(t, o, o2)
+
+	// concurrent borrows do not go over the size
+	f.Invalidate(ctx, o2)
+	done := make(chan struct{})
+	for range 3 {
+		go func() {
+			tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			_, _ = f.Borrow(tctx)
+			done <- struct{}{}
+		}()
+	}
+	for range 3 {
+		<-done
+	}
+	assert.Equal(t, 1, f.Stats().InUse)
+}
@@ -0,0 +1,155 @@
+// Package pooltls provides a pool of TLS connections that are recycled before their certificates expire.
+package pooltls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quintans/pool"
+)
+
+// Conn is the pooled object, holding a TLS connection with a completed handshake.
+// It remembers if the connection broke on a read or write, so that it is not reused.
+type Conn struct {
+	*tls.Conn
+	// peer is the leaf certificate of the first handshake, to tell if the session was renegotiated
+	peer   *x509.Certificate
+	broken atomic.Bool
+}
+
+// Read reads from the connection, flagging it as broken on errors other than timeouts.
+func (c *Conn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.check(err)
+	return n, err
+}
+
+// Write writes to the connection, flagging it as broken on errors, since a failed write breaks a TLS connection.
+func (c *Conn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if err != nil {
+		c.broken.Store(true)
+	}
+	return n, err
+}
+
+// check flags the connection as broken on a read error, unless it timed out
+func (c *Conn) check(err error) {
+	var netErr net.Error
+	if err != nil && !(errors.As(err, &netErr) && netErr.Timeout()) {
+		c.broken.Store(true)
+	}
+}
+
+type Option func(*config)
+
+type config struct {
+	dialTimeout  time.Duration
+	keepAlive    time.Duration
+	expiryMargin time.Duration
+	options      []pool.Option[Conn]
+}
+
+// DialTimeout sets the maximum amount of time a dial, including the handshake, will wait to complete.
+func DialTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.dialTimeout = d
+	}
+}
+
+// KeepAlive sets the interval between TCP keep-alive probes. A negative value disables them.
+func KeepAlive(d time.Duration) Option {
+	return func(c *config) {
+		c.keepAlive = d
+	}
+}
+
+// ExpiryMargin sets how long before the expiry of the peer certificates a connection stops being reused.
+func ExpiryMargin(d time.Duration) Option {
+	return func(c *config) {
+		c.expiryMargin = d
+	}
+}
+
+// PoolOptions sets the options of the underlying pool.
+// pool.MaxLifetime should be used to recycle the connections before the session tickets expire.
+func PoolOptions(options ...pool.Option[Conn]) Option {
+	return func(c *config) {
+		c.options = append(c.options, options...)
+	}
+}
+
+// New creates a pool of TLS connections dialed to the given network address.
+func New(ctx context.Context, network, addr string, tlsConfig *tls.Config, options ...Option) (*pool.Pool[Conn], error) {
+	cfg := config{
+		dialTimeout:  5 * time.Second,
+		keepAlive:    15 * time.Second,
+		expiryMargin: time.Minute,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{
+			Timeout:   cfg.dialTimeout,
+			KeepAlive: cfg.keepAlive,
+		},
+		Config: tlsConfig,
+	}
+
+	create := func(ctx context.Context) (*Conn, error) {
+		c, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, fmt.Errorf("dialing tls %s %s: %w", network, addr, err)
+		}
+		tc := c.(*tls.Conn)
+		conn := &Conn{Conn: tc}
+		if certs := tc.ConnectionState().PeerCertificates; len(certs) > 0 {
+			conn.peer = certs[0]
+		}
+		return conn, nil
+	}
+	expire := func(_ context.Context, c *Conn) {
+		_ = c.Close()
+	}
+
+	validate := func(ctx context.Context, c *Conn) (bool, error) {
+		return Validate(ctx, c, cfg.expiryMargin)
+	}
+
+	opts := append([]pool.Option[Conn]{pool.Validate(validate)}, cfg.options...)
+	return pool.New(ctx, create, expire, opts...)
+}
+
+// Validate checks that the connection did not break on a read or write, that the handshake is complete,
+// that the session was not renegotiated with another peer certificate and that no peer certificate expires within the margin.
+// The socket is not probed for a remote close, since post handshake messages, like TLS 1.3 session tickets,
+// may be pending and cannot be told apart without consuming them.
+func Validate(_ context.Context, c *Conn, expiryMargin time.Duration) (bool, error) {
+	if c.broken.Load() {
+		return false, nil
+	}
+	state := c.ConnectionState()
+	if !state.HandshakeComplete {
+		return false, nil
+	}
+	if c.peer != nil && (len(state.PeerCertificates) == 0 || !c.peer.Equal(state.PeerCertificates[0])) {
+		return false, nil
+	}
+
+	deadline := time.Now().Add(expiryMargin)
+	for _, cert := range state.PeerCertificates {
+		if deadline.After(cert.NotAfter) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,77 @@
+package pooltls_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quintans/pool/pooltls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateExpiryMargin(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	tlsConfig := &tls.Config{RootCAs: roots, ServerName: "example.com"}
+
+	p, err := pooltls.New(ctx, "tcp", srv.Listener.Addr().String(), tlsConfig)
+	require.NoError(t, err)
+
+	c1, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, c1)
+
+	c2, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.Same(t, c1, c2)
+	p.Return(ctx, c2)
+
+	// the certificate expires long before the margin
+	margin := time.Until(srv.Certificate().NotAfter) + time.Hour
+	p, err = pooltls.New(ctx, "tcp", srv.Listener.Addr().String(), tlsConfig, pooltls.ExpiryMargin(margin))
+	require.NoError(t, err)
+
+	c1, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	p.Return(ctx, c1)
+
+	c2, err = p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, c1, c2)
+}
+
+func TestBrokenConnection(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	tlsConfig := &tls.Config{RootCAs: roots, ServerName: "example.com"}
+
+	p, err := pooltls.New(ctx, "tcp", srv.Listener.Addr().String(), tlsConfig)
+	require.NoError(t, err)
+
+	// a failed write breaks the connection
+	c1, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	require.NoError(t, c1.SetWriteDeadline(time.Now().Add(-time.Second)))
+	_, err = c1.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	require.Error(t, err)
+	p.Return(ctx, c1)
+
+	c2, err := p.Borrow(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, c1, c2)
+}
@@ -0,0 +1,85 @@
+// Package poolworker provides a dynamic worker pool, where each pooled object is a goroutine waiting for tasks.
+// Workers are started on demand, up to the pool size, and idle workers exit after the idle timeout.
+package poolworker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quintans/pool"
+)
+
+// Worker is the pooled object, owning a goroutine that runs the submitted tasks.
+type Worker struct {
+	tasks   chan func()
+	once    sync.Once
+	expired atomic.Bool
+}
+
+type Pool struct {
+	pool *pool.Pool[Worker]
+}
+
+// New creates a worker pool.
+// By default a running task is never reclaimed by the borrow timeout.
+func New(ctx context.Context, options ...pool.Option[Worker]) (*Pool, error) {
+	p := &Pool{}
+
+	opts := append([]pool.Option[Worker]{
+		pool.BorrowTimeout[Worker](time.Duration(math.MaxInt64)),
+		pool.Validate(validate),
+	}, options...)
+	wp, err := pool.New(ctx, p.create, expire, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating worker pool: %w", err)
+	}
+	p.pool = wp
+
+	return p, nil
+}
+
+func (p *Pool) create(context.Context) (*Worker, error) {
+	w := &Worker{
+		tasks: make(chan func()),
+	}
+	go func() {
+		for task := range w.tasks {
+			task()
+			if !w.expired.Load() {
+				p.pool.Return(context.Background(), w)
+			}
+		}
+	}()
+	return w, nil
+}
+
+func expire(_ context.Context, w *Worker) {
+	w.once.Do(func() {
+		w.expired.Store(true)
+		close(w.tasks)
+	})
+}
+
+func validate(_ context.Context, w *Worker) (bool, error) {
+	return !w.expired.Load(), nil
+}
+
+// Submit runs the task in an available worker, waiting for one if all are busy.
+// It returns once the task was handed to a worker, without waiting for it to complete.
+func (p *Pool) Submit(ctx context.Context, task func()) error {
+	w, err := p.pool.Borrow(ctx)
+	if err != nil {
+		return fmt.Errorf("submitting task: %w", err)
+	}
+	w.tasks <- task
+	return nil
+}
+
+// Pool returns the underlying pool
+func (p *Pool) Pool() *pool.Pool[Worker] {
+	return p.pool
+}
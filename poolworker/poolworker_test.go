@@ -0,0 +1,43 @@
+package poolworker_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quintans/pool"
+	"github.com/quintans/pool/poolworker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitIsBoundedBySize(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := poolworker.New(ctx, pool.Size[poolworker.Worker](2))
+	require.NoError(t, err)
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		err := p.Submit(ctx, func() {
+			defer wg.Done()
+			n := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			running.Add(-1)
+		})
+		require.NoError(t, err)
+	}
+
+	wg.Wait()
+	assert.Equal(t, int32(2), maxRunning.Load())
+}
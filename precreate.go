@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type precreate struct {
+	misses   int
+	interval time.Duration
+	target   int
+	// count is the number of misses since the start of the interval
+	count   int
+	since   time.Time
+	pending bool
+}
+
+// Precreate creates idle objects in the background, up to target idle objects, when more than misses borrows
+// had to create an object within the interval, instead of getting an idle one.
+// This takes the creation latency off the borrowers when the load ramps up.
+func Precreate[T any](misses int, interval time.Duration, target int) Option[T] {
+	return func(p *Pool[T]) {
+		p.precreate = &precreate{
+			misses:   misses,
+			interval: interval,
+			target:   target,
+		}
+	}
+}
+
+// missed accounts for a borrow that had to create an object, asking the janitor to precreate objects if they are frequent
+func (p *Pool[T]) missed(now time.Time) {
+	pc := p.precreate
+	if pc == nil {
+		return
+	}
+	if now.Sub(pc.since) > pc.interval {
+		pc.since = now
+		pc.count = 0
+	}
+	pc.count++
+	if pc.count <= pc.misses {
+		return
+	}
+
+	pc.since = now
+	pc.count = 0
+	pc.pending = true
+	select {
+	case p.replenish <- struct{}{}:
+	default:
+		// already requested
+	}
+}
+
+// precreateIdle creates the idle objects requested by missed, without holding the lock while creating them
+func (p *Pool[T]) precreateIdle(ctx context.Context) error {
+	pc := p.precreate
+	if pc == nil || !pc.pending {
+		return nil
+	}
+	pc.pending = false
+
+	for !p.closed && len(p.unlocked) < pc.target && p.objectCount() < p.size && p.allowCreate() {
+		c, err := p.startCreation()
+		if errors.Is(err, errNoBudget) {
+			return nil
+		}
+
+		p.creating++
+		p.mutex.Unlock()
+		o, err := c.run(p.callbackContext(ctx))
+		p.mutex.Lock()
+		p.creating--
+
+		o, err = p.endCreation(ctx, c, o, err)
+		if err != nil {
+			p.signal()
+			return fmt.Errorf("on precreating idle objects: %w", err)
+		}
+		p.park(ctx, o)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package pool
+
+import (
+	"context"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+// MemoryPressure makes the janitor shed idle objects whenever the given channel signals memory pressure,
+// expiring half of the idle objects beyond the min idle on each signal, so that repeated signals shed progressively.
+// See MemoryLimitPressure for a signal based on the Go memory limit.
+func MemoryPressure[T any](pressure <-chan struct{}) Option[T] {
+	return func(p *Pool[T]) {
+		p.pressure = pressure
+	}
+}
+
+// relieve expires half of the idle objects beyond the min idle, on memory pressure
+func (p *Pool[T]) relieve(ctx context.Context) {
+	p.mutex.Lock()
+	n := (len(p.unlocked) - p.minIdle + 1) / 2
+	p.mutex.Unlock()
+	if n > 0 {
+		p.RemoveIdle(ctx, n)
+	}
+}
+
+// MemoryLimitPressure signals memory pressure, every interval, while the memory used by the Go runtime
+// is above the given fraction of the memory limit set with debug.SetMemoryLimit or GOMEMLIMIT.
+// Nothing is signaled if there is no memory limit. It stops when the context is done.
+func MemoryLimitPressure(ctx context.Context, threshold float64, interval time.Duration) <-chan struct{} {
+	pressure := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if memoryUsage() > threshold {
+					select {
+					case pressure <- struct{}{}:
+					default:
+						// not handled yet
+					}
+				}
+			}
+		}
+	}()
+	return pressure
+}
+
+// memoryUsage returns the fraction of the memory limit used by the Go runtime, or zero if there is no limit
+func memoryUsage() float64 {
+	limit := debug.SetMemoryLimit(-1)
+	if limit <= 0 || limit == math.MaxInt64 {
+		return 0
+	}
+
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	used := samples[0].Value.Uint64() - samples[1].Value.Uint64()
+	return float64(used) / float64(limit)
+}
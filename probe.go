@@ -0,0 +1,50 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RequireInitialObject makes New create an object, kept idle, to check that the objects can be created,
+// even without min idle or with Lazy. The creation is tried up to attempts times, waiting backoff between them,
+// and New fails if none succeeds, closing the partially built pool.
+func RequireInitialObject[T any](attempts int, backoff time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.initialAttempts = attempts
+		p.initialBackoff = backoff
+	}
+}
+
+// probe creates the initial object, see RequireInitialObject
+func (p *Pool[T]) probe(ctx context.Context) error {
+	if p.initialAttempts <= 0 {
+		return nil
+	}
+
+	var err error
+	for i := range p.initialAttempts {
+		if i > 0 {
+			timer := time.NewTimer(p.initialBackoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("on creating the initial object: %w", ctx.Err())
+			case <-timer.C:
+			}
+		}
+
+		p.mutex.Lock()
+		var o *T
+		o, err = p.newObject(ctx)
+		if err == nil {
+			p.unlocked[o] = p.newEntry()
+			p.trackPeaks()
+		}
+		p.mutex.Unlock()
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("on creating the initial object after %d attempts: %w", p.initialAttempts, err)
+}
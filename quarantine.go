@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// Quarantine keeps up to max objects that fail validation, instead of expiring them,
+// validating them again on every janitor run, so that they are rescued after a transient failure.
+// Objects still failing after maxTime are expired. Quarantined objects count for the size.
+// It pays off when creating an object is far more expensive than the occasional false negative validation.
+func Quarantine[T any](max int, maxTime time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.quarantine = map[*T]*entry{}
+		p.quarantineCap = max
+		p.quarantineTime = maxTime
+	}
+}
+
+// quarantined quarantines an object that failed validation, returning false if there is no room for it
+func (p *Pool[T]) quarantined(o *T, e *entry, now time.Time) bool {
+	if p.quarantine == nil || p.closed || len(p.quarantine) >= p.quarantineCap {
+		return false
+	}
+	e.quarantinedAt = now
+	p.quarantine[o] = e
+	return true
+}
+
+// rescue validates the quarantined objects again, making the valid ones idle and expiring the ones quarantined for too long.
+// Each object is borrowed while being validated, like with the deep validation.
+func (p *Pool[T]) rescue(ctx context.Context) {
+	if p.quarantine == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	suspects := make([]*T, 0, len(p.quarantine))
+	for o := range p.quarantine {
+		suspects = append(suspects, o)
+	}
+	p.mutex.Unlock()
+
+	for _, o := range suspects {
+		if ctx.Err() != nil {
+			return
+		}
+
+		p.mutex.Lock()
+		e, ok := p.quarantine[o]
+		if !ok || p.closed {
+			p.mutex.Unlock()
+			continue
+		}
+		delete(p.quarantine, o)
+		if time.Since(e.quarantinedAt) > p.quarantineTime {
+			p.destroy(ctx, o)
+			p.signal()
+			p.mutex.Unlock()
+			continue
+		}
+		// borrowed from now on, so that it is not reclaimed as abandoned while validated
+		e.since = time.Now()
+		p.locked[o] = e
+		p.mutex.Unlock()
+
+		valid, err := p.revalidate(p.callbackContext(ctx), o)
+
+		p.mutex.Lock()
+		if err != nil {
+			p.failed(err)
+		}
+		if p.locked[o] != e {
+			// reclaimed or expired in the meantime
+			p.mutex.Unlock()
+			continue
+		}
+		if err == nil && valid {
+			e.quarantinedAt = time.Time{}
+			p.release(ctx, o)
+		} else {
+			delete(p.locked, o)
+			if !p.quarantined(o, e, e.quarantinedAt) {
+				p.destroy(ctx, o)
+				p.signal()
+			}
+		}
+		p.mutex.Unlock()
+	}
+}
+
+// revalidate validates a quarantined object with the deep validation, if there is one
+func (p *Pool[T]) revalidate(ctx context.Context, o *T) (bool, error) {
+	if p.deepValidate != nil {
+		return p.deepValidate(ctx, o)
+	}
+	return p.validate(ctx, o)
+}
@@ -0,0 +1,108 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// CreateRateLimit limits the rate at which objects are created, allowing bursts of up to burst objects.
+// Borrowers needing a new object wait for their turn, while the janitor skips the creation.
+func CreateRateLimit[T any](r rate.Limit, burst int) Option[T] {
+	return func(p *Pool[T]) {
+		p.limiter = rate.NewLimiter(r, burst)
+	}
+}
+
+// throttle waits, without holding the pool lock, until n objects can be created.
+// It returns true if the lock was released, meaning that the pool state must be reevaluated.
+func (p *Pool[T]) throttle(ctx context.Context, n int) (bool, error) {
+	if p.limiter == nil {
+		return false, nil
+	}
+
+	r := p.limiter.ReserveN(time.Now(), n)
+	if !r.OK() {
+		return false, fmt.Errorf("creating %d objects exceeds the rate limit burst", n)
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return false, nil
+	}
+
+	p.mutex.Unlock()
+	defer p.mutex.Lock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		r.Cancel()
+		return true, ctx.Err()
+	case <-timer.C:
+		return true, nil
+	}
+}
+
+// allowCreate checks if an object can be created now, without waiting
+func (p *Pool[T]) allowCreate() bool {
+	return !p.softClosed && (p.limiter == nil || p.limiter.Allow())
+}
+
+// AdmissionPolicy is what happens to a borrow that exceeds the admission rate limit
+type AdmissionPolicy int
+
+const (
+	// AdmissionWait makes the borrower wait for its turn
+	AdmissionWait AdmissionPolicy = iota
+	// AdmissionReject fails the borrow with ErrRateLimited
+	AdmissionReject
+)
+
+// BorrowRateLimit limits the rate of borrows of each borrower label, see WithLabel, allowing bursts of up to burst borrows.
+// Unlabeled borrows share the same limit. Borrows with WithNoWait are always rejected when over the limit.
+func BorrowRateLimit[T any](r rate.Limit, burst int, policy AdmissionPolicy) Option[T] {
+	return func(p *Pool[T]) {
+		p.admission = &admission{
+			limit:    r,
+			burst:    burst,
+			policy:   policy,
+			limiters: map[string]*rate.Limiter{},
+		}
+	}
+}
+
+type admission struct {
+	mutex    sync.Mutex
+	limit    rate.Limit
+	burst    int
+	policy   AdmissionPolicy
+	limiters map[string]*rate.Limiter
+}
+
+// admit applies the admission rate limit of the borrower label to n borrows
+func (a *admission) admit(ctx context.Context, n int, cfg borrowConfig) error {
+	if a == nil {
+		return nil
+	}
+
+	a.mutex.Lock()
+	limiter, ok := a.limiters[cfg.label]
+	if !ok {
+		limiter = rate.NewLimiter(a.limit, a.burst)
+		a.limiters[cfg.label] = limiter
+	}
+	a.mutex.Unlock()
+
+	if a.policy == AdmissionReject || cfg.noWait {
+		if !limiter.AllowN(time.Now(), n) {
+			return ErrRateLimited
+		}
+		return nil
+	}
+
+	return limiter.WaitN(ctx, n)
+}
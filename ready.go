@@ -0,0 +1,93 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// readyInterval is how often WaitReady checks the pool
+const readyInterval = 10 * time.Millisecond
+
+// WaitReady blocks until there are at least min idle objects and an idle object validates successfully,
+// so that it can back a readiness probe. If there are no objects, one is created.
+// Failed validations and creations are retried until the context is done.
+func (p *Pool[T]) WaitReady(ctx context.Context) error {
+	ticker := time.NewTicker(readyInterval)
+	defer ticker.Stop()
+
+	for {
+		ready, err := p.ready(ctx)
+		if err != nil {
+			return fmt.Errorf("on wait ready: %w", err)
+		}
+		if ready {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("on wait ready: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// ready checks if there are min idle objects and if one of them is valid
+func (p *Pool[T]) ready(ctx context.Context) (bool, error) {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return false, ErrPoolClosed
+	}
+	if len(p.unlocked) < p.minIdle {
+		p.mutex.Unlock()
+		return false, nil
+	}
+
+	var o *T
+	for o = range p.unlocked {
+		break
+	}
+	if o == nil {
+		// objects in use are known to work
+		inUse := len(p.locked) > 0
+		p.mutex.Unlock()
+		if inUse {
+			return true, nil
+		}
+
+		o, err := p.Borrow(ctx, WithNoWait())
+		if errors.Is(err, ErrPoolClosed) {
+			return false, err
+		}
+		if err != nil {
+			return false, nil
+		}
+		p.Return(ctx, o)
+		return true, nil
+	}
+
+	e := p.unlocked[o]
+	delete(p.unlocked, o)
+	e.since = time.Now()
+	p.locked[o] = e
+	p.mutex.Unlock()
+
+	valid, err := p.validate(p.callbackContext(ctx), o)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if err != nil {
+		p.failed(err)
+	}
+	if err == nil && valid {
+		p.release(ctx, o)
+		return true, nil
+	}
+	delete(p.locked, o)
+	p.invalidated(o)
+	p.destroy(ctx, o)
+	p.signal()
+	return false, nil
+}
@@ -0,0 +1,48 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+)
+
+var errReentrantBorrow = errors.New("reentrant borrow")
+
+// goroutineID returns the id of the current goroutine, parsed from its stack header
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// the header is "goroutine 123 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+// checkReentrancy reports a borrower about to wait for an object while holding another one from the pool,
+// since it may wait for itself. It only works with DebugStacks, panicking if strict and logging otherwise.
+func (p *Pool[T]) checkReentrancy(ctx context.Context) {
+	if !p.debugStacks {
+		return
+	}
+
+	id := goroutineID()
+	for _, e := range p.locked {
+		if e.goroutine != id {
+			continue
+		}
+		err := fmt.Errorf("%w: goroutine %d waits for an object while holding one borrowed at:\n%s\nwaiting at:\n%s",
+			errReentrantBorrow, id, e.stack, debug.Stack())
+		if p.strict {
+			panic("pool: " + err.Error())
+		}
+		p.errLogger(p.withInfo(ctx), err, "borrower may be waiting for itself")
+		return
+	}
+}
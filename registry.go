@@ -0,0 +1,120 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// DefaultRegistry is the registry where pools are registered, unless another one is set with the Register option.
+var DefaultRegistry = NewRegistry()
+
+// Registered is the non generic view of a pool kept by a Registry
+type Registered interface {
+	Name() string
+	Attributes() []slog.Attr
+	Stats() Stats
+	Close(ctx context.Context)
+}
+
+// NamedStats are the stats of a named pool
+type NamedStats struct {
+	Name string
+	Stats
+}
+
+// Registry tracks the live pools. Pools are removed when closed.
+type Registry struct {
+	mutex sync.Mutex
+	pools map[Registered]struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		pools: map[Registered]struct{}{},
+	}
+}
+
+// Name sets the name of the pool, used to identify it in a registry.
+func Name[T any](name string) Option[T] {
+	return func(p *Pool[T]) {
+		p.name = name
+	}
+}
+
+// Register sets the registry where the pool is registered. Nil means the pool is not registered.
+func Register[T any](registry *Registry) Option[T] {
+	return func(p *Pool[T]) {
+		p.registry = registry
+	}
+}
+
+func (p *Pool[T]) Name() string {
+	return p.name
+}
+
+func (r *Registry) add(p Registered) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.pools[p] = struct{}{}
+}
+
+func (r *Registry) remove(p Registered) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.pools, p)
+}
+
+// Pools returns the live pools, sorted by name.
+func (r *Registry) Pools() []Registered {
+	r.mutex.Lock()
+	pools := make([]Registered, 0, len(r.pools))
+	for p := range r.pools {
+		pools = append(pools, p)
+	}
+	r.mutex.Unlock()
+
+	sort.SliceStable(pools, func(i, j int) bool {
+		return pools[i].Name() < pools[j].Name()
+	})
+	return pools
+}
+
+// Stats returns the stats of every live pool, sorted by name.
+func (r *Registry) Stats() []NamedStats {
+	pools := r.Pools()
+	stats := make([]NamedStats, 0, len(pools))
+	for _, p := range pools {
+		stats = append(stats, NamedStats{
+			Name:  p.Name(),
+			Stats: p.Stats(),
+		})
+	}
+	return stats
+}
+
+// ForEach calls fn for every live pool, sorted by name, for example to total the stats of the pools of a backend.
+func (r *Registry) ForEach(fn func(Registered)) {
+	for _, p := range r.Pools() {
+		fn(p)
+	}
+}
+
+// AggregateStats returns the stats of all the live pools added up, see Stats.Add.
+func (r *Registry) AggregateStats() Stats {
+	var total Stats
+	r.ForEach(func(p Registered) {
+		total = total.Add(p.Stats())
+	})
+	return total
+}
+
+// CloseAll closes every live pool.
+func (r *Registry) CloseAll(ctx context.Context) {
+	for _, p := range r.Pools() {
+		p.Close(ctx)
+	}
+}
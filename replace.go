@@ -0,0 +1,37 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ReplaceOnExpire makes every expired object, idle or borrowed, be replaced by a new idle object in the background,
+// as long as the size allows it, so that the population does not dip below the warm set.
+func ReplaceOnExpire[T any]() Option[T] {
+	return func(p *Pool[T]) {
+		p.replaceOnExpire = true
+	}
+}
+
+// replaceExpired creates an idle object for each object expired since the last call, see ReplaceOnExpire
+func (p *Pool[T]) replaceExpired(ctx context.Context) error {
+	n := p.replacements
+	p.replacements = 0
+	for range n {
+		if p.objectCount() >= p.size || !p.allowCreate() {
+			return nil
+		}
+		o, err := p.newObject(ctx)
+		if errors.Is(err, errNoBudget) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("on replacing expired objects: %w", err)
+		}
+		p.unlocked[o] = p.newEntry()
+		p.trackPeaks()
+	}
+	p.signal()
+	return nil
+}
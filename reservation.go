@@ -0,0 +1,42 @@
+package pool
+
+// admissible checks if n objects can be borrowed by the class
+// without using the capacity reserved for other classes that is not yet in use
+func (p *Pool[T]) admissible(class string, n int) bool {
+	if len(p.reservations) == 0 {
+		return true
+	}
+
+	// objects being created for a borrower are as good as borrowed
+	inUse := map[string]int{}
+	borrowed := len(p.locked)
+	for _, e := range p.locked {
+		if _, ok := p.reservations[e.class]; ok {
+			inUse[e.class]++
+		}
+	}
+	for c, n := range p.pending {
+		borrowed += n
+		if _, ok := p.reservations[c]; ok {
+			inUse[c] += n
+		}
+	}
+
+	unmet := 0
+	for c, reserved := range p.reservations {
+		if c != class && inUse[c] < reserved {
+			unmet += reserved - inUse[c]
+		}
+	}
+
+	return p.size+p.maxOverflow-borrowed-unmet >= n
+}
+
+// creatingFor accounts for n objects being created for a borrower of the class, or for their end if n is negative
+func (p *Pool[T]) creatingFor(class string, n int) {
+	p.creating += n
+	p.pending[class] += n
+	if p.pending[class] == 0 {
+		delete(p.pending, class)
+	}
+}
@@ -0,0 +1,32 @@
+package pool
+
+// ring keeps the last values added to it
+type ring[E any] struct {
+	values []E
+	next   int
+	full   bool
+}
+
+func newRing[E any](n int) *ring[E] {
+	return &ring[E]{values: make([]E, n)}
+}
+
+// add keeps a value, overwriting the oldest one when full
+func (r *ring[E]) add(v E) {
+	r.values[r.next] = v
+	r.next = (r.next + 1) % len(r.values)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the kept values, from the oldest to the newest
+func (r *ring[E]) snapshot() []E {
+	if r == nil {
+		return nil
+	}
+	if !r.full {
+		return append([]E(nil), r.values[:r.next]...)
+	}
+	return append(append([]E(nil), r.values[r.next:]...), r.values[:r.next]...)
+}
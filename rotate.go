@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Rotate gradually recycles all the existing objects, instead of all at once like InvalidateAll.
+// On each janitor run, up to rate idle objects of a previous generation are expired and replaced by new ones.
+// Borrowed objects are rotated after being returned.
+func (p *Pool[T]) Rotate(ctx context.Context, rate int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("on rotate: %w", ErrPoolClosed)
+	}
+
+	if rate < 1 {
+		rate = 1
+	}
+	p.generation++
+	p.rotationRate = rate
+
+	return nil
+}
+
+// rotate replaces up to the rotation rate of idle objects from previous generations
+func (p *Pool[T]) rotate(ctx context.Context) error {
+	if p.rotationRate == 0 {
+		return nil
+	}
+
+	rotated := 0
+	for o, e := range p.unlocked {
+		if rotated == p.rotationRate {
+			break
+		}
+		if e.generation == p.generation {
+			continue
+		}
+		if !p.allowCreate() {
+			break
+		}
+
+		delete(p.unlocked, o)
+		p.destroy(ctx, o)
+		rotated++
+
+		n, err := p.newObject(ctx)
+		if errors.Is(err, errNoBudget) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("on rotating: %w", err)
+		}
+		p.unlocked[n] = p.newEntry()
+	}
+
+	if !p.hasPreviousGeneration() {
+		p.rotationRate = 0
+	}
+
+	return nil
+}
+
+func (p *Pool[T]) hasPreviousGeneration() bool {
+	for _, e := range p.unlocked {
+		if e.generation != p.generation {
+			return true
+		}
+	}
+	for _, e := range p.locked {
+		if e.generation != p.generation {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Rotator is a facade over a pool that can be replaced, like on a configuration reload.
+// Borrows go to the current pool while the previous ones are soft closed, draining the objects still borrowed from them.
+type Rotator[T any] struct {
+	mutex    sync.Mutex
+	current  *Pool[T]
+	previous []*Pool[T]
+	// owners are the pools of the borrowed objects
+	owners map[*T]*Pool[T]
+}
+
+// NewRotator creates a rotator routing the borrows to the given pool.
+func NewRotator[T any](p *Pool[T]) *Rotator[T] {
+	return &Rotator[T]{
+		current: p,
+		owners:  map[*T]*Pool[T]{},
+	}
+}
+
+// Rotate routes the new borrows to the given pool and soft closes the current one, see SoftClose.
+func (r *Rotator[T]) Rotate(p *Pool[T]) {
+	r.mutex.Lock()
+	old := r.current
+	r.current = p
+	previous := r.previous[:0]
+	for _, prev := range r.previous {
+		if !prev.IsClosed() {
+			previous = append(previous, prev)
+		}
+	}
+	r.previous = append(previous, old)
+	r.mutex.Unlock()
+
+	old.SoftClose()
+}
+
+// Current returns the pool the borrows are routed to.
+func (r *Rotator[T]) Current() *Pool[T] {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.current
+}
+
+func (r *Rotator[T]) Borrow(ctx context.Context, options ...BorrowOption) (*T, error) {
+	p := r.Current()
+	o, err := p.Borrow(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	r.owners[o] = p
+	r.mutex.Unlock()
+
+	return o, nil
+}
+
+// Return returns the object to the pool it was borrowed from.
+func (r *Rotator[T]) Return(ctx context.Context, o *T) {
+	r.owner(o).Return(ctx, o)
+}
+
+// Invalidate invalidates the object in the pool it was borrowed from.
+func (r *Rotator[T]) Invalidate(ctx context.Context, o *T) {
+	r.owner(o).Invalidate(ctx, o)
+}
+
+// owner returns the pool the object was borrowed from, forgetting it
+func (r *Rotator[T]) owner(o *T) *Pool[T] {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	p, ok := r.owners[o]
+	if !ok {
+		// not borrowed through the rotator, so the pool reports the misuse
+		return r.current
+	}
+	delete(r.owners, o)
+	return p
+}
+
+// Close closes the current and the previous pools.
+func (r *Rotator[T]) Close(ctx context.Context) {
+	r.mutex.Lock()
+	pools := append([]*Pool[T]{r.current}, r.previous...)
+	r.previous = nil
+	r.mutex.Unlock()
+
+	for _, p := range pools {
+		p.Close(ctx)
+	}
+}
@@ -0,0 +1,71 @@
+package pool
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Profile is a capacity applied during a time window.
+type Profile struct {
+	// Weekdays where the profile applies. Empty means every day.
+	Weekdays []time.Weekday
+	// From and To are the time of day, in local time, delimiting the window [From, To).
+	// If To is before From, the window wraps around midnight.
+	From, To time.Duration
+	// Size is like the Size option, zero meaning no limit
+	Size    int
+	MinIdle int
+}
+
+func (pr Profile) active(now time.Time) bool {
+	if len(pr.Weekdays) > 0 && !slices.Contains(pr.Weekdays, now.Weekday()) {
+		return false
+	}
+	y, m, d := now.Date()
+	tod := now.Sub(time.Date(y, m, d, 0, 0, 0, 0, now.Location()))
+	if pr.From <= pr.To {
+		return tod >= pr.From && tod < pr.To
+	}
+	return tod >= pr.From || tod < pr.To
+}
+
+// Schedule sets time based capacity profiles, applied by the janitor.
+// The first active profile sets the size and minIdle of the pool.
+// When no profile is active, the values set by the Size and MinIdle options are used.
+func Schedule[T any](profiles ...Profile) Option[T] {
+	return func(p *Pool[T]) {
+		p.schedule = profiles
+	}
+}
+
+// applySchedule applies the active profile, if it changed since the last time
+func (p *Pool[T]) applySchedule(ctx context.Context, now time.Time) {
+	if len(p.schedule) == 0 {
+		return
+	}
+
+	active := -1
+	for i, pr := range p.schedule {
+		if pr.active(now) {
+			active = i
+			break
+		}
+	}
+	if active == p.activeProfile {
+		return
+	}
+	p.activeProfile = active
+
+	size, minIdle := p.baseSize, p.baseMinIdle
+	if active >= 0 {
+		size, minIdle = capped(p.schedule[active].Size), max(p.schedule[active].MinIdle, 0)
+	}
+	if size > p.size {
+		p.signal()
+	}
+	p.size, p.minIdle = size, minIdle
+	// drop the idle objects beyond the new size
+	for p.objectCount() > p.size && p.evictIdle(ctx) {
+	}
+}
@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// ServeStale keeps the idle objects that fail validation on borrow, as degraded, instead of expiring them,
+// to be handed out, flagged by Lease.Degraded, when no object can be created because the create function is failing.
+// For read mostly caches, a possibly stale session beats an error.
+// Degraded objects are kept apart from the idle ones, so they are not counted as idle nor handed out by other borrows,
+// do not count for the size when creating an object, and are expired once an object is created.
+func ServeStale[T any]() Option[T] {
+	return func(p *Pool[T]) {
+		p.degraded = map[*T]*entry{}
+	}
+}
+
+// degradedCount returns the number of idle degraded objects
+func (p *Pool[T]) degradedCount() int {
+	return len(p.degraded)
+}
+
+// borrowDegraded borrows an idle degraded object, returning nil if there is none
+func (p *Pool[T]) borrowDegraded(cfg borrowConfig) (*T, *entry) {
+	now := time.Now()
+	for o, e := range p.degraded {
+		if p.stale(e, now) {
+			continue
+		}
+		delete(p.degraded, o)
+		e.borrow(now, cfg)
+		p.locked[o] = e
+		p.emit(EventBorrowed, o, nil)
+		p.borrowed(e)
+		p.trackPeaks()
+		return o, e
+	}
+	return nil, nil
+}
+
+// evictDegraded expires the idle degraded objects that are idle for too long or stale, returning how many
+func (p *Pool[T]) evictDegraded(ctx context.Context, now time.Time) int {
+	evicted := 0
+	for o, e := range p.degraded {
+		if now.Sub(e.since) > p.idleTimeout+e.jitter || p.stale(e, now) {
+			delete(p.degraded, o)
+			p.destroy(ctx, o)
+			p.emit(EventEvicted, o, nil)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// retireDegraded expires the idle degraded objects, once objects can be created again
+func (p *Pool[T]) retireDegraded(ctx context.Context) {
+	for o := range p.degraded {
+		delete(p.degraded, o)
+		p.destroy(ctx, o)
+	}
+}
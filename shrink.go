@@ -0,0 +1,33 @@
+package pool
+
+import (
+	"context"
+	"sort"
+)
+
+// RemoveIdle expires up to n of the longest idle objects, without going below the min idle,
+// returning how many were expired. It is meant to shed resources on demand, like on memory pressure.
+func (p *Pool[T]) RemoveIdle(ctx context.Context, n int) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return 0
+	}
+
+	idle := make([]*T, 0, len(p.unlocked))
+	for o := range p.unlocked {
+		idle = append(idle, o)
+	}
+	sort.Slice(idle, func(i, j int) bool {
+		return p.unlocked[idle[i]].since.Before(p.unlocked[idle[j]].since)
+	})
+
+	n = min(n, len(idle)-p.minIdle)
+	for _, o := range idle[:max(n, 0)] {
+		delete(p.unlocked, o)
+		p.destroy(ctx, o)
+		p.emit(EventEvicted, o, nil)
+	}
+	return max(n, 0)
+}
@@ -0,0 +1,40 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WarnSlowCreate warns when creating an object takes longer than d.
+func WarnSlowCreate[T any](d time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.slowCreate = d
+	}
+}
+
+// WarnSlowBorrowWait warns when a borrower waits longer than d for an object.
+func WarnSlowBorrowWait[T any](d time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.slowBorrowWait = d
+	}
+}
+
+// WarnLogger sets the logger of the warnings. By default they are logged with slog.
+func WarnLogger[T any](warnLogger func(ctx context.Context, msg string, attrs ...slog.Attr)) Option[T] {
+	return func(p *Pool[T]) {
+		p.warnLogger = warnLogger
+	}
+}
+
+// warnSlow warns if the operation took longer than the threshold
+func (p *Pool[T]) warnSlow(ctx context.Context, msg string, start time.Time, threshold time.Duration) {
+	if threshold <= 0 || start.IsZero() {
+		return
+	}
+	took := time.Since(start)
+	if took <= threshold {
+		return
+	}
+	p.warnLogger(p.withInfo(ctx), msg, slog.Duration("took", took), slog.Duration("threshold", threshold))
+}
@@ -0,0 +1,19 @@
+package pool
+
+// SoftClose stops the pool from creating objects, while it keeps serving the idle objects and accepting returns.
+// The pool is closed once there are no objects left, as they are expired by the borrowers or by the janitor.
+// Borrowers that find no idle object wait for one to be returned.
+func (p *Pool[T]) SoftClose() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.softClosed = true
+	p.drained()
+}
+
+// drained closes a soft closed pool without objects
+func (p *Pool[T]) drained() {
+	if p.softClosed && p.objectCount() == 0 {
+		p.cancel()
+	}
+}
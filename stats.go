@@ -0,0 +1,288 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Stats is a snapshot of the pool state
+type Stats struct {
+	// Size is the maximum number of objects, zero if unbounded
+	Size int
+	// Idle is the number of objects available to be borrowed
+	Idle int
+	// InUse is the number of borrowed objects
+	InUse int
+	// Waiters is the number of borrowers waiting for an object
+	Waiters int
+	// Creating is the number of objects being created
+	Creating int
+	// Quarantined is the number of objects that failed validation, waiting to be rescued, see Quarantine
+	Quarantined int
+	// Degraded is the number of objects that failed validation, kept to be served stale, see ServeStale
+	Degraded int
+	// LastSweep is when the janitor last ran without errors
+	LastSweep time.Time
+	// LastError is the last error creating or validating an object, or running the janitor, and LastErrorAt is when it happened
+	LastError   error
+	LastErrorAt time.Time
+	// Peaks are the maximum counts since the start or the last ResetPeaks
+	Peaks Peaks
+	// IdleCost and InUseCost are the total cost of the idle and of the borrowed objects, see Cost
+	IdleCost  int64
+	InUseCost int64
+	// Labels are the stats of each borrower label, see WithLabel
+	Labels map[string]LabelStats
+	// Journal are the last lifecycle events, from the oldest to the newest, see Journal
+	Journal []Event
+}
+
+// Add returns the stats of two pools added up, for totals across pools.
+// Unbounded pools add nothing to the size, the last error is the latest one and the label stats are merged.
+// Peaks, the last sweep and the journal are left out, since they do not add up.
+func (s Stats) Add(other Stats) Stats {
+	total := Stats{
+		Size:        s.Size + other.Size,
+		Idle:        s.Idle + other.Idle,
+		InUse:       s.InUse + other.InUse,
+		Waiters:     s.Waiters + other.Waiters,
+		Creating:    s.Creating + other.Creating,
+		Quarantined: s.Quarantined + other.Quarantined,
+		Degraded:    s.Degraded + other.Degraded,
+		IdleCost:    s.IdleCost + other.IdleCost,
+		InUseCost:   s.InUseCost + other.InUseCost,
+	}
+	total.LastError, total.LastErrorAt = s.LastError, s.LastErrorAt
+	if other.LastErrorAt.After(s.LastErrorAt) {
+		total.LastError, total.LastErrorAt = other.LastError, other.LastErrorAt
+	}
+	for _, labels := range []map[string]LabelStats{s.Labels, other.Labels} {
+		for label, l := range labels {
+			if total.Labels == nil {
+				total.Labels = map[string]LabelStats{}
+			}
+			t := total.Labels[label]
+			t.InUse += l.InUse
+			t.Borrows += l.Borrows
+			t.HoldTime += l.HoldTime
+			total.Labels[label] = t
+		}
+	}
+	return total
+}
+
+// String returns a compact description of the stats, for logs.
+func (s Stats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "size=%d idle=%d in_use=%d waiters=%d", s.Size, s.Idle, s.InUse, s.Waiters)
+	fmt.Fprintf(&b, " peak_idle=%d peak_in_use=%d peak_waiters=%d", s.Peaks.Idle, s.Peaks.InUse, s.Peaks.Waiters)
+	if s.Creating != 0 {
+		fmt.Fprintf(&b, " creating=%d", s.Creating)
+	}
+	if s.Quarantined != 0 {
+		fmt.Fprintf(&b, " quarantined=%d", s.Quarantined)
+	}
+	if s.Degraded != 0 {
+		fmt.Fprintf(&b, " degraded=%d", s.Degraded)
+	}
+	if s.IdleCost != 0 || s.InUseCost != 0 {
+		fmt.Fprintf(&b, " idle_cost=%d in_use_cost=%d", s.IdleCost, s.InUseCost)
+	}
+	if !s.LastSweep.IsZero() {
+		fmt.Fprintf(&b, " last_sweep=%s", s.LastSweep.Format(time.RFC3339))
+	}
+	if s.LastError != nil {
+		fmt.Fprintf(&b, " last_error=%q last_error_at=%s", s.LastError, s.LastErrorAt.Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+type statsJSON struct {
+	Size        int                  `json:"size"`
+	Idle        int                  `json:"idle"`
+	InUse       int                  `json:"in_use"`
+	Waiters     int                  `json:"waiters"`
+	Creating    int                  `json:"creating,omitempty"`
+	Quarantined int                  `json:"quarantined,omitempty"`
+	Degraded    int                  `json:"degraded,omitempty"`
+	LastSweep   *time.Time           `json:"last_sweep,omitempty"`
+	LastError   string               `json:"last_error,omitempty"`
+	LastErrorAt *time.Time           `json:"last_error_at,omitempty"`
+	Peaks       peaksJSON            `json:"peaks"`
+	IdleCost    int64                `json:"idle_cost,omitempty"`
+	InUseCost   int64                `json:"in_use_cost,omitempty"`
+	Labels      map[string]labelJSON `json:"labels,omitempty"`
+	Journal     []eventJSON          `json:"journal,omitempty"`
+}
+
+type eventJSON struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Object string    `json:"object,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+type labelJSON struct {
+	InUse    int    `json:"in_use"`
+	Borrows  int    `json:"borrows"`
+	HoldTime string `json:"hold_time"`
+}
+
+type peaksJSON struct {
+	InUse   int `json:"in_use"`
+	Idle    int `json:"idle"`
+	Waiters int `json:"waiters"`
+}
+
+// MarshalJSON encodes the stats with snake case keys, omitting the unset times and errors.
+func (s Stats) MarshalJSON() ([]byte, error) {
+	out := statsJSON{
+		Size:        s.Size,
+		Idle:        s.Idle,
+		InUse:       s.InUse,
+		Waiters:     s.Waiters,
+		Creating:    s.Creating,
+		Quarantined: s.Quarantined,
+		Degraded:    s.Degraded,
+		Peaks:       peaksJSON(s.Peaks),
+		IdleCost:    s.IdleCost,
+		InUseCost:   s.InUseCost,
+	}
+	if !s.LastSweep.IsZero() {
+		out.LastSweep = &s.LastSweep
+	}
+	if len(s.Labels) > 0 {
+		out.Labels = make(map[string]labelJSON, len(s.Labels))
+		for label, l := range s.Labels {
+			out.Labels[label] = labelJSON{InUse: l.InUse, Borrows: l.Borrows, HoldTime: l.HoldTime.String()}
+		}
+	}
+	for _, e := range s.Journal {
+		event := eventJSON{Type: e.Type.String(), Time: e.Time, Object: e.Object}
+		if e.Err != nil {
+			event.Error = e.Err.Error()
+		}
+		out.Journal = append(out.Journal, event)
+	}
+	if s.LastError != nil {
+		out.LastError = s.LastError.Error()
+		out.LastErrorAt = &s.LastErrorAt
+	}
+	return json.Marshal(out)
+}
+
+// Peaks are high-water marks of the pool
+type Peaks struct {
+	InUse   int
+	Idle    int
+	Waiters int
+}
+
+func (p *Pool[T]) Stats() Stats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.stats()
+}
+
+func (p *Pool[T]) stats() Stats {
+	var idleCost, inUseCost int64
+	if p.cost != nil {
+		for o := range p.unlocked {
+			idleCost += p.cost(o)
+		}
+		for o := range p.locked {
+			inUseCost += p.cost(o)
+		}
+	}
+
+	return Stats{
+		Size:        p.capacity(),
+		Idle:        len(p.unlocked),
+		InUse:       len(p.locked),
+		Waiters:     p.waiters,
+		Creating:    p.creating,
+		Quarantined: len(p.quarantine),
+		Degraded:    len(p.degraded),
+		LastSweep:   p.lastSweep,
+		LastError:   p.lastError,
+		LastErrorAt: p.lastErrorAt,
+		Peaks:       p.peaks,
+		IdleCost:    idleCost,
+		InUseCost:   inUseCost,
+		Labels:      p.labelStats(),
+		Journal:     p.journal.snapshot(),
+	}
+}
+
+// ResetPeaks sets the high-water marks to the current counts.
+func (p *Pool[T]) ResetPeaks() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.peaks = Peaks{}
+	p.trackPeaks()
+}
+
+// trackPeaks updates the high-water marks with the current counts
+func (p *Pool[T]) trackPeaks() {
+	p.peaks.InUse = max(p.peaks.InUse, len(p.locked))
+	p.peaks.Idle = max(p.peaks.Idle, len(p.unlocked))
+	p.peaks.Waiters = max(p.peaks.Waiters, p.waiters)
+}
+
+// Cost sets how much each object costs, like its memory footprint, to be aggregated in the stats.
+// It is called while holding the pool lock, so it must be cheap.
+func Cost[T any](cost func(*T) int64) Option[T] {
+	return func(p *Pool[T]) {
+		p.cost = cost
+	}
+}
+
+// IsClosed reports whether the pool was closed.
+func (p *Pool[T]) IsClosed() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.closed
+}
+
+// Cap returns the maximum number of objects, zero if unbounded.
+func (p *Pool[T]) Cap() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.capacity()
+}
+
+// capacity is the size, zero if unbounded
+func (p *Pool[T]) capacity() int {
+	if p.size >= unbounded {
+		return 0
+	}
+	return p.size
+}
+
+// Idle returns the number of objects available to be borrowed.
+func (p *Pool[T]) Idle() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.unlocked)
+}
+
+// InUse returns the number of borrowed objects.
+func (p *Pool[T]) InUse() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.locked)
+}
+
+// failed records the last error of the pool
+func (p *Pool[T]) failed(err error) {
+	p.lastError = err
+	p.lastErrorAt = time.Now()
+}
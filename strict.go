@@ -0,0 +1,72 @@
+package pool
+
+import "fmt"
+
+// Strict makes the pool panic on misuse, like returning an object twice, returning an object that does not
+// belong to the pool or that was expired, or borrowing from a closed pool, instead of silently coping with it.
+// It is meant to surface integration bugs during development.
+// Only the last expired objects are remembered, older ones being reported as not belonging to the pool.
+func Strict[T any]() Option[T] {
+	return func(p *Pool[T]) {
+		p.strict = true
+		p.expired = &recent[T]{
+			set:  map[*T]struct{}{},
+			ring: make([]*T, maxRemembered),
+		}
+	}
+}
+
+// maxRemembered is the number of expired objects remembered in strict mode
+const maxRemembered = 1024
+
+// recent remembers the last objects added, forgetting the oldest ones beyond its capacity
+type recent[T any] struct {
+	set  map[*T]struct{}
+	ring []*T
+	next int
+}
+
+func (r *recent[T]) add(o *T) {
+	if r.has(o) {
+		return
+	}
+	if old := r.ring[r.next]; old != nil {
+		delete(r.set, old)
+	}
+	r.ring[r.next] = o
+	r.set[o] = struct{}{}
+	r.next = (r.next + 1) % len(r.ring)
+}
+
+func (r *recent[T]) has(o *T) bool {
+	_, ok := r.set[o]
+	return ok
+}
+
+// violation panics on misuse, if strict
+func (p *Pool[T]) violation(format string, args ...any) {
+	if p.strict {
+		panic(fmt.Sprintf("pool: "+format, args...))
+	}
+}
+
+// misuse describes the misuse of an object that is not borrowed, if strict
+func (p *Pool[T]) misuse(op string, o *T) {
+	if !p.strict {
+		return
+	}
+	if _, ok := p.unlocked[o]; ok {
+		p.violation("%s of an object that is not borrowed", op)
+	}
+	if p.expired.has(o) {
+		p.violation("%s of an expired object", op)
+	}
+	p.violation("%s of an object that does not belong to the pool", op)
+}
+
+// remember keeps the expired objects, to detect their use, if strict
+func (p *Pool[T]) remember(o *T) {
+	if p.strict {
+		p.expired.add(o)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build go1.25
+
+package pool_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/quintans/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the pool only relies on the standard time and channels, so it runs on virtual time inside a synctest bubble
+
+func TestSynctestTimeouts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+
+		var expired atomic.Int32
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+			func(ctx context.Context, f *Foo) { expired.Add(1) },
+			pool.Size[Foo](2),
+			pool.IdleTimeout[Foo](time.Minute),
+			pool.BorrowTimeout[Foo](time.Hour),
+			pool.JanitorSleep[Foo](time.Second),
+			pool.Register[Foo](nil),
+		)
+		require.NoError(t, err)
+		defer p.Close(ctx)
+
+		idle, err := p.Borrow(ctx)
+		require.NoError(t, err)
+		_, err = p.Borrow(ctx)
+		require.NoError(t, err)
+
+		// the borrow deadline expires
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		_, err = p.Borrow(tctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// idle timeout
+		p.Return(ctx, idle)
+		time.Sleep(2 * time.Minute)
+		synctest.Wait()
+		assert.EqualValues(t, 1, expired.Load())
+
+		// borrow timeout
+		time.Sleep(time.Hour)
+		synctest.Wait()
+		assert.EqualValues(t, 2, expired.Load())
+	})
+}
+
+func TestSynctestKeyIdleTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+
+		kp := pool.NewKeyed[string, Foo](
+			ctx,
+			func(ctx context.Context, key string) (*Foo, error) { return &Foo{key}, nil },
+			func(ctx context.Context, f *Foo) {},
+			pool.KeyIdleTimeout[string, Foo](time.Minute),
+		)
+		defer kp.Close(ctx)
+
+		f, err := kp.Borrow(ctx, "a")
+		require.NoError(t, err)
+		kp.Return(ctx, "a", f)
+
+		time.Sleep(2 * time.Minute)
+		synctest.Wait()
+		assert.Equal(t, 0, kp.Keys())
+	})
+}
+
+func TestSynctestChurnAlarm(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+
+		var alarms, churn atomic.Int32
+		p, err := pool.New[Foo](
+			ctx,
+			func(ctx context.Context) (*Foo, error) { return &Foo{"foo"}, nil },
+			func(ctx context.Context, f *Foo) {},
+			pool.JanitorSleep[Foo](10*time.Second),
+			pool.ChurnAlarm[Foo](5, func(ctx context.Context, n int) {
+				alarms.Add(1)
+				churn.Store(int32(n))
+			}),
+			pool.Register[Foo](nil),
+		)
+		require.NoError(t, err)
+		defer p.Close(ctx)
+
+		// a minute without churn
+		time.Sleep(75 * time.Second)
+		synctest.Wait()
+		assert.Zero(t, alarms.Load())
+
+		for range 4 {
+			f, err := p.Borrow(ctx)
+			require.NoError(t, err)
+			p.Invalidate(ctx, f)
+		}
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		assert.EqualValues(t, 1, alarms.Load())
+		assert.EqualValues(t, 8, churn.Load())
+	})
+}
@@ -0,0 +1,102 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+)
+
+// TransferIdleTo moves up to n idle objects to another pool, keeping their timestamps, returning how many were moved.
+// Objects are only moved while the destination has room for them, so that warm objects are not lost when resharding.
+func (p *Pool[T]) TransferIdleTo(ctx context.Context, dst *Pool[T], n int) (int, error) {
+	if dst == p {
+		return 0, nil
+	}
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return 0, fmt.Errorf("on transfer idle: %w", ErrPoolClosed)
+	}
+	objects := map[*T]*entry{}
+	cleanups := map[*T][]func(context.Context){}
+	for o, e := range p.unlocked {
+		if len(objects) == n {
+			break
+		}
+		delete(p.unlocked, o)
+		p.unbind(o)
+		p.forget(o)
+		objects[o] = e
+		if funcs := p.takeCleanups(o); funcs != nil {
+			cleanups[o] = funcs
+		}
+	}
+	p.mutex.Unlock()
+
+	sameBudget := p.budget == dst.budget
+	moved, err := dst.adopt(objects, cleanups, sameBudget)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.budget != nil && !sameBudget {
+		for range moved {
+			p.budget.release()
+		}
+	}
+	// the objects that did not fit are kept
+	for o, e := range objects {
+		if funcs := cleanups[o]; funcs != nil {
+			p.cleanups[o] = funcs
+		}
+		if p.closed || p.objectCount() >= p.size {
+			p.destroy(ctx, o)
+			continue
+		}
+		p.unlocked[o] = e
+	}
+	if !p.closed && len(p.unlocked) < p.minIdle {
+		select {
+		case p.replenish <- struct{}{}:
+		default:
+			// already requested
+		}
+	}
+	p.drained()
+
+	if err != nil {
+		return moved, fmt.Errorf("on transfer idle: %w", err)
+	}
+	return moved, nil
+}
+
+// adopt keeps the idle objects of another pool, with their cleanups, while there is room for them, removing them from objects
+func (p *Pool[T]) adopt(objects map[*T]*entry, cleanups map[*T][]func(context.Context), sameBudget bool) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return 0, ErrPoolClosed
+	}
+
+	moved := 0
+	for o, e := range objects {
+		if p.softClosed || p.objectCount() >= p.size {
+			break
+		}
+		if !sameBudget && p.budget != nil && !p.budget.acquire() {
+			break
+		}
+		delete(objects, o)
+		e.generation = p.generation
+		p.unlocked[o] = e
+		if funcs := cleanups[o]; funcs != nil {
+			p.cleanups[o] = funcs
+		}
+		moved++
+	}
+	p.trackPeaks()
+	p.signal()
+
+	return moved, nil
+}
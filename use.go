@@ -0,0 +1,45 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidObject is returned, wrapped, by the callbacks of Use to have the object invalidated instead of returned,
+// like when a connection is found broken.
+var ErrInvalidObject = errors.New("invalid pooled object")
+
+// Use borrows an object, calls fn with it and returns it to the pool, returning the result of fn.
+// The object is invalidated instead if fn returns an error wrapping ErrInvalidObject, or if it panics.
+func Use[T, R any](ctx context.Context, p Borrower[T], fn func(context.Context, *T) (R, error)) (R, error) {
+	o, err := p.Borrow(ctx)
+	if err != nil {
+		var zero R
+		return zero, fmt.Errorf("on use: %w", err)
+	}
+
+	var result R
+	err = call(ctx, p, o, func() error {
+		var err error
+		result, err = fn(ctx, o)
+		return err
+	})
+	return result, err
+}
+
+// call calls fn with a borrowed object, returning or invalidating the object afterwards
+func call[T any](ctx context.Context, p Borrower[T], o *T, fn func() error) (err error) {
+	done := false
+	defer func() {
+		if !done || errors.Is(err, ErrInvalidObject) {
+			p.Invalidate(ctx, o)
+			return
+		}
+		p.Return(ctx, o)
+	}()
+
+	err = fn()
+	done = true
+	return err
+}
@@ -0,0 +1,114 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// View is a facade over a pool that limits how many objects can be borrowed through it at the same time.
+// It shares the objects of the pool, allowing per component quotas over a single pool.
+type View[T any] struct {
+	pool    *Pool[T]
+	cond    *Cond
+	mutex   sync.Mutex
+	max     int
+	waiters int
+	// pending is the number of borrows in progress in the pool
+	pending  int
+	borrowed map[*T]struct{}
+}
+
+var _ Borrower[struct{}] = (*View[struct{}])(nil)
+
+// View creates a view over the pool allowing at most maxConcurrent borrowed objects.
+func (p *Pool[T]) View(maxConcurrent int) *View[T] {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &View[T]{
+		pool:     p,
+		cond:     NewCond(),
+		max:      maxConcurrent,
+		borrowed: map[*T]struct{}{},
+	}
+}
+
+func (v *View[T]) Borrow(ctx context.Context, options ...BorrowOption) (*T, error) {
+	cfg := newBorrowConfig(options)
+
+	v.mutex.Lock()
+	for len(v.borrowed)+v.pending >= v.max {
+		if cfg.noWait {
+			v.mutex.Unlock()
+			return nil, fmt.Errorf("on view borrow: %w", ErrPoolExhausted)
+		}
+
+		seq := v.cond.Sequence()
+		v.waiters++
+		v.mutex.Unlock()
+		err := v.cond.WaitSeq(ctx, seq)
+		v.mutex.Lock()
+		v.waiters--
+		if err != nil {
+			v.mutex.Unlock()
+			return nil, fmt.Errorf("on view borrow while waiting: %w", err)
+		}
+	}
+	// hold the slot while borrowing from the pool
+	v.pending++
+	v.mutex.Unlock()
+
+	o, err := v.pool.Borrow(ctx, options...)
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.pending--
+	if err != nil {
+		v.cond.Broadcast()
+		return nil, err
+	}
+	v.borrowed[o] = struct{}{}
+	return o, nil
+}
+
+func (v *View[T]) Return(ctx context.Context, o *T) {
+	if v.release(o) {
+		v.pool.Return(ctx, o)
+	}
+}
+
+func (v *View[T]) Invalidate(ctx context.Context, o *T) {
+	if v.release(o) {
+		v.pool.Invalidate(ctx, o)
+	}
+}
+
+// release frees the slot of the object, returning false if it was not borrowed through this view
+func (v *View[T]) release(o *T) bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if _, ok := v.borrowed[o]; !ok || o == nil {
+		return false
+	}
+	delete(v.borrowed, o)
+	v.cond.Broadcast()
+	return true
+}
+
+// Stats returns the view limits and usage, with the idle objects of the underlying pool.
+func (v *View[T]) Stats() Stats {
+	idle := v.pool.Stats().Idle
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return Stats{
+		Size:    v.max,
+		Idle:    idle,
+		InUse:   len(v.borrowed) + v.pending,
+		Waiters: v.waiters,
+	}
+}
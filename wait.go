@@ -0,0 +1,205 @@
+package pool
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// WaitStrategy is how borrowers wait for objects to be released.
+// The sequence is taken while holding the pool lock, so that a Broadcast happening before WaitSeq is not missed.
+// Broadcast must wake up every waiter, since each one checks again if it can be served, according to its priority and class.
+type WaitStrategy interface {
+	Sequence() uint64
+	WaitSeq(ctx context.Context, seq uint64) error
+	Broadcast()
+}
+
+var (
+	_ WaitStrategy = (*Cond)(nil)
+	_ WaitStrategy = (*SpinCond)(nil)
+	_ WaitStrategy = (*HandoffCond)(nil)
+	_ WaitStrategy = (*SemaCond)(nil)
+)
+
+// Waiting sets how borrowers wait for objects. Defaults to a Cond.
+// Cond wakes up every waiter by closing a shared channel, SpinCond spins before parking,
+// HandoffCond hands a wake up to the channel of each waiter and SemaCond wakes up the waiters in arrival order.
+func Waiting[T any](strategy WaitStrategy) Option[T] {
+	return func(p *Pool[T]) {
+		p.cond = strategy
+	}
+}
+
+// SpinCond is a Cond that spins for a while before parking a waiter, trading CPU for wake up latency.
+// It pays off when objects are held for microseconds, like small buffers, but not for long held objects, like GPUs.
+type SpinCond struct {
+	*Cond
+	spin time.Duration
+}
+
+// NewSpinCond creates a SpinCond that spins for the given duration before parking.
+func NewSpinCond(spin time.Duration) *SpinCond {
+	return &SpinCond{
+		Cond: NewCond(),
+		spin: spin,
+	}
+}
+
+// Wait waits for the condition to be signaled or for the context to be cancelled, spinning first.
+func (s *SpinCond) Wait(ctx context.Context) error {
+	return s.WaitSeq(ctx, s.Sequence())
+}
+
+// WaitSeq is like Cond.WaitSeq, but it spins before parking.
+func (s *SpinCond) WaitSeq(ctx context.Context, seq uint64) error {
+	deadline := time.Now().Add(s.spin)
+	for time.Now().Before(deadline) {
+		if s.Sequence() != seq {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		runtime.Gosched()
+	}
+	return s.Cond.WaitSeq(ctx, seq)
+}
+
+// HandoffCond hands a wake up to the channel of each waiter, instead of closing a channel shared by all of them,
+// and forgets the waiters that give up, so that nothing is allocated while there is no one waiting.
+// It suits pools with a few, long held, objects, like GPUs, where waiters are few.
+type HandoffCond struct {
+	mutex   sync.Mutex
+	seq     uint64
+	waiters []chan struct{}
+}
+
+// NewHandoffCond creates a HandoffCond.
+func NewHandoffCond() *HandoffCond {
+	return &HandoffCond{}
+}
+
+// Sequence returns the current sequence number, to be used with WaitSeq.
+func (s *HandoffCond) Sequence() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.seq
+}
+
+// Wait waits for the condition to be signaled or for the context to be cancelled.
+func (s *HandoffCond) Wait(ctx context.Context) error {
+	return s.WaitSeq(ctx, s.Sequence())
+}
+
+// WaitSeq is like Cond.WaitSeq, waiting on a channel of its own.
+func (s *HandoffCond) WaitSeq(ctx context.Context, seq uint64) error {
+	s.mutex.Lock()
+	if s.seq != seq {
+		s.mutex.Unlock()
+		return nil
+	}
+	ch := make(chan struct{}, 1)
+	s.waiters = append(s.waiters, ch)
+	s.mutex.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, c := range s.waiters {
+		if c == ch {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			break
+		}
+	}
+	return ctx.Err()
+}
+
+// Broadcast hands a wake up to every waiter.
+func (s *HandoffCond) Broadcast() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.seq++
+	for i, ch := range s.waiters {
+		ch <- struct{}{}
+		s.waiters[i] = nil
+	}
+	s.waiters = s.waiters[:0]
+}
+
+// SemaCond wakes up the waiters through a semaphore, releasing one permit per waiter on Broadcast,
+// so that they are woken up in arrival order, instead of all at once, easing the contention on the pool lock
+// when thousands of borrowers wait for small objects, like buffers.
+// A waiter giving up right as it is woken up may leave its permit to a later waiter, waking it up spuriously,
+// which borrowers tolerate, since they check again if they can be served.
+type SemaCond struct {
+	mutex   sync.Mutex
+	seq     uint64
+	waiting int
+	sema    *Cond
+}
+
+// NewSemaCond creates a SemaCond.
+func NewSemaCond() *SemaCond {
+	return &SemaCond{
+		sema: NewCond(),
+	}
+}
+
+// Sequence returns the current sequence number, to be used with WaitSeq.
+func (s *SemaCond) Sequence() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.seq
+}
+
+// Wait waits for the condition to be signaled or for the context to be cancelled.
+func (s *SemaCond) Wait(ctx context.Context) error {
+	return s.WaitSeq(ctx, s.Sequence())
+}
+
+// WaitSeq is like Cond.WaitSeq, waiting for a permit of the semaphore.
+func (s *SemaCond) WaitSeq(ctx context.Context, seq uint64) error {
+	s.mutex.Lock()
+	if s.seq != seq {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.waiting++
+	s.mutex.Unlock()
+
+	err := s.sema.WaitPermit(ctx)
+	if err == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// not counted anymore if there was a Broadcast in the meantime
+	if s.seq == seq {
+		s.waiting--
+	}
+	return err
+}
+
+// Broadcast releases a permit for every waiter.
+func (s *SemaCond) Broadcast() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.seq++
+	n := s.waiting
+	s.waiting = 0
+	s.sema.SignalN(n)
+}
@@ -0,0 +1,70 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errWaitTimeout is the cause of a borrow giving up after the wait timeout
+var errWaitTimeout = errors.New("wait timeout")
+
+// WaitTimeout caps how long a borrower waits for an object, independently of the deadline of its context,
+// so that borrowers with a context without deadline do not hang forever. The borrow then fails with ErrPoolExhausted.
+func WaitTimeout[T any](d time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.waitTimeout = d
+	}
+}
+
+// WaitShare limits the time a borrower with a deadline waits for an object to the given share, between 0 and 1,
+// of the time left until the deadline, so that the remainder is left for creating an object.
+// When the share is exhausted, the borrow fails with ErrPoolExhausted.
+func WaitShare[T any](share float64) Option[T] {
+	return func(p *Pool[T]) {
+		p.waitShare = share
+	}
+}
+
+// CreateBudget stops a borrower with a deadline from waiting for an object when less than d is left until the deadline,
+// since there would be no time left to create one.
+// When the budget is reached, the borrow fails with ErrPoolExhausted.
+func CreateBudget[T any](d time.Duration) Option[T] {
+	return func(p *Pool[T]) {
+		p.createBudget = d
+	}
+}
+
+// waitContext bounds the waiting of a borrower according to the wait timeout, the wait share and the create budget
+func (p *Pool[T]) waitContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	bounded := ok && (p.waitShare > 0 || p.createBudget > 0)
+	if p.waitTimeout <= 0 && !bounded {
+		return ctx, noCancel
+	}
+
+	waitCtx, cancelTimeout := ctx, noCancel
+	if p.waitTimeout > 0 {
+		waitCtx, cancelTimeout = context.WithTimeoutCause(ctx, p.waitTimeout, errWaitTimeout)
+	}
+	if !bounded {
+		return waitCtx, cancelTimeout
+	}
+
+	now := time.Now()
+	waitDeadline := deadline.Add(-p.createBudget)
+	if p.waitShare > 0 && p.waitShare < 1 {
+		d := now.Add(time.Duration(float64(deadline.Sub(now)) * p.waitShare))
+		if d.Before(waitDeadline) {
+			waitDeadline = d
+		}
+	}
+	waitCtx, cancelDeadline := context.WithDeadline(waitCtx, waitDeadline)
+	return waitCtx, func() {
+		cancelDeadline()
+		cancelTimeout()
+	}
+}
+
+// noCancel is the cancel function of a context that was not derived
+func noCancel() {}
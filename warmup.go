@@ -0,0 +1,82 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// WarmupConcurrency creates up to n of the min idle objects in parallel when warming up the pool,
+// instead of one at a time. The warmup happens on New or, with Lazy, in the background.
+func WarmupConcurrency[T any](n int) Option[T] {
+	return func(p *Pool[T]) {
+		p.warmupConcurrency = n
+	}
+}
+
+// warmup creates the min idle objects, in parallel if WarmupConcurrency is set
+func (p *Pool[T]) warmup(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.warmupConcurrency <= 1 {
+		return p.keepMinIdle(ctx)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		first error
+	)
+	sem := make(chan struct{}, p.warmupConcurrency)
+	missing := min(p.minIdle-len(p.unlocked), p.size-p.objectCount())
+	for range missing {
+		p.mutex.Unlock()
+		sem <- struct{}{}
+		p.mutex.Lock()
+
+		if first != nil || p.closed || !p.allowCreate() {
+			<-sem
+			break
+		}
+		c, err := p.startCreation()
+		if err != nil {
+			// no budget left
+			<-sem
+			break
+		}
+
+		p.creating++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o, err := c.run(p.callbackContext(ctx))
+			<-sem
+
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			p.creating--
+			o, err = p.endCreation(ctx, c, o, err)
+			switch {
+			case err != nil:
+				if first == nil {
+					first = err
+				}
+			case p.closed:
+				p.destroy(ctx, o)
+			default:
+				p.unlocked[o] = p.newEntry()
+				p.trackPeaks()
+				p.signal()
+			}
+		}()
+	}
+
+	p.mutex.Unlock()
+	wg.Wait()
+	p.mutex.Lock()
+
+	if first != nil {
+		return fmt.Errorf("on keeping the idle minimum: %w", first)
+	}
+	return nil
+}